@@ -4,7 +4,11 @@ import (
 	"context"
 	"fmt"
 	"github.com/go-redis/redis/v8"
+	"github.com/goccy/go-yaml"
 	"github.com/icinga/icingadb/internal/command"
+	"github.com/icinga/icingadb/internal/health"
+	"github.com/icinga/icingadb/internal/httpserver"
+	"github.com/icinga/icingadb/internal/pprof"
 	"github.com/icinga/icingadb/pkg/common"
 	"github.com/icinga/icingadb/pkg/icingadb"
 	"github.com/icinga/icingadb/pkg/icingadb/history"
@@ -13,6 +17,7 @@ import (
 	"github.com/icinga/icingadb/pkg/icingaredis"
 	"github.com/icinga/icingadb/pkg/icingaredis/telemetry"
 	"github.com/icinga/icingadb/pkg/logging"
+	"github.com/icinga/icingadb/pkg/metrics"
 	"github.com/icinga/icingadb/pkg/utils"
 	"github.com/okzk/sdnotify"
 	"github.com/pkg/errors"
@@ -20,6 +25,7 @@ import (
 	"golang.org/x/sync/errgroup"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
@@ -57,11 +63,66 @@ func run() int {
 
 	logger.Info("Starting Icinga DB")
 
+	if ec, err := yaml.MarshalWithOptions(cmd.Config.EffectiveConfig(), yaml.Flow(true)); err != nil {
+		logger.Warnw("Can't marshal effective configuration", zap.Error(err))
+	} else {
+		logger.Infof("Effective configuration: %s", ec)
+	}
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+
+	if address := cmd.Config.Metrics.Address; address != "" {
+		go func() {
+			logger.Infof("Serving metrics at http://%s/metrics", address)
+			if err := httpserver.ListenAndServe(ctx, address, metrics.Handler()); err != nil {
+				logger.Errorw("Can't serve metrics", zap.Error(err))
+			}
+		}()
+	}
+
+	if address := cmd.Config.Pprof.Address; address != "" {
+		go func() {
+			logger.Infof("Serving profiling data at http://%s/debug/pprof/", address)
+			if err := httpserver.ListenAndServe(ctx, address, pprof.Handler()); err != nil {
+				logger.Errorw("Can't serve profiling data", zap.Error(err))
+			}
+		}()
+	}
+
 	db, err := cmd.Database(logs.GetChildLogger("database"))
 	if err != nil {
 		logger.Fatalf("%+v", errors.Wrap(err, "can't create database connection pool from config"))
 	}
 	defer db.Close()
+
+	rc, err := cmd.Redis(logs.GetChildLogger("redis"))
+	if err != nil {
+		logger.Fatalf("%+v", errors.Wrap(err, "can't create Redis client from config"))
+	}
+
+	// Serve health checks before connecting, so that /healthz already succeeds while Icinga DB is starting.
+	if address := cmd.Config.Health.Address; address != "" {
+		checker := health.Checker{
+			Redis: func(ctx context.Context) error {
+				return rc.Ping(ctx).Err()
+			},
+			Database: db.PingContext,
+			Synced: func() bool {
+				_, ok := telemetry.LastSuccessfulSync.Load()
+
+				return ok
+			},
+		}
+
+		go func() {
+			logger.Infof("Serving health checks at http://%s/healthz and http://%s/readyz", address, address)
+			if err := httpserver.ListenAndServe(ctx, address, checker.Handler()); err != nil {
+				logger.Errorw("Can't serve health checks", zap.Error(err))
+			}
+		}()
+	}
+
 	{
 		logger.Infof("Connecting to database at '%s'", utils.JoinHostPort(cmd.Config.Database.Host, cmd.Config.Database.Port))
 		err := db.Ping()
@@ -74,12 +135,12 @@ func run() int {
 		logger.Fatalf("%+v", err)
 	}
 
-	rc, err := cmd.Redis(logs.GetChildLogger("redis"))
-	if err != nil {
-		logger.Fatalf("%+v", errors.Wrap(err, "can't create Redis client from config"))
-	}
 	{
-		logger.Infof("Connecting to Redis at '%s'", utils.JoinHostPort(cmd.Config.Redis.Host, cmd.Config.Redis.Port))
+		if sentinels := cmd.Config.Redis.Sentinels; len(sentinels) > 0 {
+			logger.Infof("Connecting to Redis master '%s' via Sentinels %s", cmd.Config.Redis.MasterName, strings.Join(sentinels, ", "))
+		} else {
+			logger.Infof("Connecting to Redis at '%s'", utils.JoinHostPort(cmd.Config.Redis.Host, cmd.Config.Redis.Port))
+		}
 		_, err := rc.Ping(context.Background()).Result()
 		if err != nil {
 			logger.Fatalf("%+v", errors.Wrap(err, "can't connect to Redis"))
@@ -95,9 +156,6 @@ func run() int {
 		go monitorRedisSchema(logger, rc, pos)
 	}
 
-	ctx, cancelCtx := context.WithCancel(context.Background())
-	defer cancelCtx()
-
 	// Use dedicated connections for heartbeat and HA to ensure that heartbeats are always processed and
 	// the instance table is updated. Otherwise, the connections can be too busy due to the synchronization of
 	// configuration, status, history, etc., which can lead to handover / takeover loops because
@@ -131,7 +189,7 @@ func run() int {
 		ha.Close(ctx)
 		cancelCtx()
 	}()
-	s := icingadb.NewSync(db, rc, logs.GetChildLogger("config-sync"))
+	s := icingadb.NewSync(db, rc, logs.GetChildLogger("config-sync"), cmd.Config.Logging.IdSampleSize)
 	hs := history.NewSync(db, rc, logs.GetChildLogger("history-sync"))
 	rt := icingadb.NewRuntimeUpdates(db, rc, logs.GetChildLogger("runtime-updates"))
 	ods := overdue.NewSync(db, rc, logs.GetChildLogger("overdue-sync"))
@@ -206,6 +264,7 @@ func run() int {
 						})
 
 						syncStart := time.Now()
+						s.StartSummary()
 						atomic.StoreInt64(&telemetry.OngoingSyncStartMilli, syncStart.UnixMilli())
 
 						logger.Info("Starting config sync")
@@ -280,6 +339,17 @@ func run() int {
 							return nil
 						})
 
+						g.Go(func() error {
+							configInitSync.Wait()
+							stateInitSync.Wait()
+
+							// Also log the summary if the sync has been aborted, e.g. because a type failed,
+							// before the error is returned from the group.
+							s.LogSummary(synctx.Err() != nil)
+
+							return nil
+						})
+
 						g.Go(func() error {
 							configInitSync.Wait()
 
@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// checkTimeout limits the time a single dependency check may take, so that probes do not hang on a stuck connection.
+const checkTimeout = 5 * time.Second
+
+// Checker provides the checks reported by Handler.
+type Checker struct {
+	// Redis checks whether the Redis connection is healthy.
+	Redis func(context.Context) error
+	// Database checks whether the database connection is healthy.
+	Database func(context.Context) error
+	// Synced reports whether an initial config sync has completed at least once.
+	Synced func() bool
+}
+
+// Dependency describes the state of a single dependency in the /readyz response.
+type Dependency struct {
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Readiness is the body of the /readyz response.
+type Readiness struct {
+	Ready    bool       `json:"ready"`
+	Redis    Dependency `json:"redis"`
+	Database Dependency `json:"database"`
+	Synced   bool       `json:"synced"`
+}
+
+// Handler returns a handler that serves /healthz, which succeeds as long as the process is serving requests,
+// and /readyz, which only succeeds if Redis and the database are healthy and the config has been synced.
+// Both respond with a JSON body and 503 Service Unavailable if they do not succeed.
+func (c Checker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, struct {
+			Alive bool `json:"alive"`
+		}{true})
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		readiness := c.Readiness(r.Context())
+
+		status := http.StatusOK
+		if !readiness.Ready {
+			status = http.StatusServiceUnavailable
+		}
+
+		writeJSON(w, status, readiness)
+	})
+
+	return mux
+}
+
+// Readiness runs all checks and returns their results.
+func (c Checker) Readiness(ctx context.Context) Readiness {
+	r := Readiness{
+		Redis:    check(ctx, c.Redis),
+		Database: check(ctx, c.Database),
+		Synced:   c.Synced(),
+	}
+	r.Ready = r.Redis.Healthy && r.Database.Healthy && r.Synced
+
+	return r
+}
+
+// check calls fn with a timeout and converts its result into a Dependency.
+func check(ctx context.Context, fn func(context.Context) error) Dependency {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	if err := fn(ctx); err != nil {
+		return Dependency{Healthy: false, Error: err.Error()}
+	}
+
+	return Dependency{Healthy: true}
+}
+
+// writeJSON writes v as JSON response body with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
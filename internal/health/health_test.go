@@ -0,0 +1,72 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChecker_Handler(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("connection refused") }
+
+	tests := []struct {
+		name     string
+		checker  Checker
+		status   int
+		expected Readiness
+	}{{
+		name:    "Ready",
+		checker: Checker{Redis: ok, Database: ok, Synced: func() bool { return true }},
+		status:  http.StatusOK,
+		expected: Readiness{
+			Ready:    true,
+			Redis:    Dependency{Healthy: true},
+			Database: Dependency{Healthy: true},
+			Synced:   true,
+		},
+	}, {
+		name:    "NotSynced",
+		checker: Checker{Redis: ok, Database: ok, Synced: func() bool { return false }},
+		status:  http.StatusServiceUnavailable,
+		expected: Readiness{
+			Redis:    Dependency{Healthy: true},
+			Database: Dependency{Healthy: true},
+		},
+	}, {
+		name:    "DatabaseDown",
+		checker: Checker{Redis: ok, Database: failing, Synced: func() bool { return true }},
+		status:  http.StatusServiceUnavailable,
+		expected: Readiness{
+			Redis:    Dependency{Healthy: true},
+			Database: Dependency{Error: "connection refused"},
+			Synced:   true,
+		},
+	}}
+
+	for _, st := range tests {
+		t.Run(st.name, func(t *testing.T) {
+			srv := httptest.NewServer(st.checker.Handler())
+			defer srv.Close()
+
+			res, err := http.Get(srv.URL + "/healthz")
+			require.NoError(t, err)
+			_ = res.Body.Close()
+			require.Equal(t, http.StatusOK, res.StatusCode, "/healthz should not depend on the checks")
+
+			res, err = http.Get(srv.URL + "/readyz")
+			require.NoError(t, err)
+			defer func() { _ = res.Body.Close() }()
+			require.Equal(t, st.status, res.StatusCode)
+			require.Equal(t, "application/json", res.Header.Get("Content-Type"))
+
+			var actual Readiness
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+			require.Equal(t, st.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package httpserver
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ListenAndServe serves handler on a dedicated HTTP server listening on the specified address
+// until ctx is canceled.
+func ListenAndServe(ctx context.Context, address string, handler http.Handler) error {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return errors.Wrap(err, "can't listen on "+address)
+	}
+
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+
+	if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errors.Wrap(err, "can't serve HTTP on "+address)
+	}
+
+	return nil
+}
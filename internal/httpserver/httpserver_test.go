@@ -0,0 +1,54 @@
+package httpserver
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestListenAndServe(t *testing.T) {
+	// Reserve a free port.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "served")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- ListenAndServe(ctx, address, handler) }()
+
+	var res *http.Response
+	require.Eventually(t, func() bool {
+		res, err = http.Get("http://" + address + "/")
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	body, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "served", string(body))
+
+	cancel()
+	require.NoError(t, <-done)
+
+	_, err = http.Get("http://" + address + "/")
+	require.Error(t, err, "server should be gone after the context is canceled")
+}
+
+func TestListenAndServe_AddressInUse(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = l.Close() }()
+
+	err = ListenAndServe(context.Background(), l.Addr().String(), http.NotFoundHandler())
+	require.ErrorContains(t, err, "can't listen on "+l.Addr().String())
+}
@@ -0,0 +1,48 @@
+package pprof
+
+import (
+	"context"
+	"github.com/icinga/icingadb/internal/health"
+	"github.com/icinga/icingadb/pkg/metrics"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	srv := httptest.NewServer(Handler())
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/debug/pprof/goroutine?debug=1")
+	require.NoError(t, err)
+	defer func() { _ = res.Body.Close() }()
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Contains(t, string(body), "goroutine profile")
+}
+
+func TestHandler_Disabled(t *testing.T) {
+	// Importing net/http/pprof registers the profiling endpoints on http.DefaultServeMux,
+	// so make sure that the other servers Icinga DB runs do not expose them.
+	ok := func(context.Context) error { return nil }
+	handlers := map[string]http.Handler{
+		"Metrics": metrics.Handler(),
+		"Health":  health.Checker{Redis: ok, Database: ok, Synced: func() bool { return true }}.Handler(),
+	}
+
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(handler)
+			defer srv.Close()
+
+			res, err := http.Get(srv.URL + "/debug/pprof/")
+			require.NoError(t, err)
+			_ = res.Body.Close()
+			require.Equal(t, http.StatusNotFound, res.StatusCode)
+		})
+	}
+}
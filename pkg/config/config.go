@@ -8,6 +8,7 @@ import (
 	"github.com/jessevdk/go-flags"
 	"github.com/pkg/errors"
 	"io/ioutil"
+	"net"
 	"os"
 )
 
@@ -17,6 +18,9 @@ type Config struct {
 	Redis     Redis     `yaml:"redis"`
 	Logging   Logging   `yaml:"logging"`
 	Retention Retention `yaml:"retention"`
+	Pprof     Pprof     `yaml:"pprof"`
+	Metrics   Metrics   `yaml:"metrics"`
+	Health    Health    `yaml:"health"`
 }
 
 // Validate checks constraints in the supplied configuration and returns an error if they are violated.
@@ -33,10 +37,50 @@ func (c *Config) Validate() error {
 	if err := c.Retention.Validate(); err != nil {
 		return err
 	}
+	if err := c.Pprof.Validate(); err != nil {
+		return err
+	}
+	if err := c.Metrics.Validate(); err != nil {
+		return err
+	}
+	if err := c.Health.Validate(); err != nil {
+		return err
+	}
 
 	return nil
 }
 
+// EffectiveConfig returns a copy of c with all passwords redacted,
+// so that the fully resolved configuration can be logged safely.
+func (c *Config) EffectiveConfig() *Config {
+	ec := *c
+	ec.Database.Password = redact(ec.Database.Password)
+	ec.Redis.Password = redact(ec.Redis.Password)
+	ec.Redis.SentinelPassword = redact(ec.Redis.SentinelPassword)
+
+	return &ec
+}
+
+// redact returns a placeholder for the given secret, or the empty string if the secret is not set.
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+
+	return "<redacted>"
+}
+
+// parseAddress checks that the address configured for the named option is in the form host:port
+// and returns its host.
+func parseAddress(option, address string) (string, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid %s address %q", option, address)
+	}
+
+	return host, nil
+}
+
 // Flags defines CLI flags.
 type Flags struct {
 	// Version decides whether to just print the version and exit.
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"github.com/creasty/defaults"
+	"github.com/goccy/go-yaml"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_EffectiveConfig(t *testing.T) {
+	c := &Config{}
+	require.NoError(t, defaults.Set(c))
+
+	c.Database.Password = "db-secret"
+	c.Redis.Password = "redis-secret"
+	c.Redis.SentinelPassword = "sentinel-secret"
+
+	ec := c.EffectiveConfig()
+	require.Equal(t, "<redacted>", ec.Database.Password)
+	require.Equal(t, "<redacted>", ec.Redis.Password)
+	require.Equal(t, "<redacted>", ec.Redis.SentinelPassword)
+	require.Equal(t, "db-secret", c.Database.Password, "original config must not be modified")
+	require.Equal(t, "redis-secret", c.Redis.Password, "original config must not be modified")
+
+	out, err := yaml.MarshalWithOptions(ec, yaml.Flow(true))
+	require.NoError(t, err)
+	require.NotContains(t, string(out), "db-secret")
+	require.NotContains(t, string(out), "redis-secret")
+	require.NotContains(t, string(out), "sentinel-secret")
+
+	for _, tunable := range []string{
+		"type: mysql", "max_connections: 16", "max_connections_per_table: 8",
+		"max_placeholders_per_statement: 8192", "max_rows_per_transaction: 8192",
+		"block_timeout: 1s", "hmget_count: 4096", "hscan_count: 4096", "max_hmget_connections: 8",
+		"timeout: 30s", "xread_count: 4096", "interval: 20s", "count: 5000",
+	} {
+		require.Contains(t, string(out), tunable)
+	}
+}
+
+func TestConfig_EffectiveConfig_EmptyPassword(t *testing.T) {
+	ec := (&Config{}).EffectiveConfig()
+	require.Empty(t, ec.Database.Password, "unset passwords should stay empty")
+	require.Empty(t, ec.Redis.Password, "unset passwords should stay empty")
+}
+
+func TestPprof_Validate(t *testing.T) {
+	for _, address := range []string{"", "localhost:6060", "127.0.0.1:6060", "[::1]:6060"} {
+		require.NoError(t, (&Pprof{Address: address}).Validate(), "address %q should be valid", address)
+	}
+
+	for _, address := range []string{":6060", "0.0.0.0:6060", "192.0.2.1:6060", "example.com:6060", "localhost"} {
+		require.Error(t, (&Pprof{Address: address}).Validate(), "address %q should be invalid", address)
+	}
+}
+
+func TestRedis_Validate_Sentinel(t *testing.T) {
+	newRedis := func(host, masterName string, sentinels ...string) *Redis {
+		r := &Redis{}
+		require.NoError(t, defaults.Set(r))
+		r.Host = host
+		r.MasterName = masterName
+		r.Sentinels = sentinels
+
+		return r
+	}
+
+	require.NoError(t, newRedis("localhost", "").Validate())
+	require.NoError(t, newRedis("", "icingadb", "sentinel1:26379", "sentinel2:26379").Validate())
+	require.Error(t, newRedis("", "").Validate(), "host or sentinels are required")
+	require.Error(t, newRedis("", "", "sentinel1:26379").Validate(), "master_name is required with sentinels")
+	require.Error(t, newRedis("localhost", "icingadb").Validate(), "sentinels are required with master_name")
+}
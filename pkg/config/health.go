@@ -0,0 +1,18 @@
+package config
+
+// Health defines the configuration of the optional health check endpoint.
+type Health struct {
+	// Address to serve /healthz and /readyz on. Health checks are disabled if empty.
+	Address string `yaml:"address"`
+}
+
+// Validate checks constraints in the supplied Health configuration and returns an error if they are violated.
+func (h *Health) Validate() error {
+	if h.Address == "" {
+		return nil
+	}
+
+	_, err := parseAddress("health", h.Address)
+
+	return err
+}
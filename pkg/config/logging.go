@@ -15,6 +15,8 @@ type Logging struct {
 	Output string        `yaml:"output"`
 	// Interval for periodic logging.
 	Interval time.Duration `yaml:"interval" default:"20s"`
+	// Maximum number of IDs logged at debug level for each insert, update and delete of a sync.
+	IdSampleSize int `yaml:"id_sample_size" default:"10"`
 
 	logging.Options `yaml:"options"`
 }
@@ -27,6 +29,10 @@ func (l *Logging) Validate() error {
 		return errors.New("periodic logging interval must be positive")
 	}
 
+	if l.IdSampleSize < 0 {
+		return errors.New("id_sample_size cannot be negative")
+	}
+
 	if l.Output == "" {
 		if _, ok := os.LookupEnv("NOTIFY_SOCKET"); ok {
 			// When started by systemd, NOTIFY_SOCKET is set by systemd for Type=notify supervised services,
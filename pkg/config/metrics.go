@@ -0,0 +1,18 @@
+package config
+
+// Metrics defines the configuration of the optional Prometheus metrics endpoint.
+type Metrics struct {
+	// Address to serve /metrics on. Metrics are disabled if empty.
+	Address string `yaml:"address"`
+}
+
+// Validate checks constraints in the supplied Metrics configuration and returns an error if they are violated.
+func (m *Metrics) Validate() error {
+	if m.Address == "" {
+		return nil
+	}
+
+	_, err := parseAddress("metrics", m.Address)
+
+	return err
+}
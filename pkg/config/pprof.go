@@ -0,0 +1,35 @@
+package config
+
+import (
+	"github.com/pkg/errors"
+	"net"
+)
+
+// Pprof defines the configuration of the optional profiling endpoint.
+type Pprof struct {
+	// Address to serve net/http/pprof on. Profiling is disabled if empty.
+	Address string `yaml:"address"`
+}
+
+// Validate checks constraints in the supplied Pprof configuration and returns an error if they are violated.
+// As profiling data reveals internals of the process, only loopback addresses are allowed.
+func (p *Pprof) Validate() error {
+	if p.Address == "" {
+		return nil
+	}
+
+	host, err := parseAddress("pprof", p.Address)
+	if err != nil {
+		return err
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return errors.Errorf("pprof address %q must be a loopback address, e.g. localhost:6060", p.Address)
+	}
+
+	return nil
+}
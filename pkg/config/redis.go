@@ -13,25 +13,38 @@ import (
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	"net"
+	"runtime"
 	"strings"
 	"time"
 )
 
 // Redis defines Redis client configuration.
 type Redis struct {
-	Host       string              `yaml:"host"`
-	Port       int                 `yaml:"port" default:"6380"`
-	Password   string              `yaml:"password"`
-	TlsOptions TLS                 `yaml:",inline"`
-	Options    icingaredis.Options `yaml:"options"`
+	Host             string              `yaml:"host"`
+	Port             int                 `yaml:"port" default:"6380"`
+	Username         string              `yaml:"username"`
+	Password         string              `yaml:"password"`
+	MasterName       string              `yaml:"master_name"`
+	Sentinels        []string            `yaml:"sentinels"`
+	SentinelPassword string              `yaml:"sentinel_password"`
+	TlsOptions       TLS                 `yaml:",inline"`
+	ServerName       string              `yaml:"servername"`
+	Options          icingaredis.Options `yaml:"options"`
 }
 
 type ctxDialerFunc = func(ctx context.Context, network, addr string) (net.Conn, error)
 
 // NewClient prepares Redis client configuration,
-// calls redis.NewClient, but returns *icingaredis.Client.
+// calls redis.NewClient, or redis.NewFailoverClient if Sentinels are configured,
+// but returns *icingaredis.Client.
 func (r *Redis) NewClient(logger *logging.Logger) (*icingaredis.Client, error) {
-	tlsConfig, err := r.TlsOptions.MakeConfig(r.Host)
+	// Without a host, i.e. with Sentinel, the server name is taken from the address that is dialed.
+	serverName := r.Host
+	if r.ServerName != "" {
+		serverName = r.ServerName
+	}
+
+	tlsConfig, err := r.TlsOptions.MakeConfig(serverName)
 	if err != nil {
 		return nil, err
 	}
@@ -45,29 +58,54 @@ func (r *Redis) NewClient(logger *logging.Logger) (*icingaredis.Client, error) {
 		dialer = (&tls.Dialer{NetDialer: dl, Config: tlsConfig}).DialContext
 	}
 
-	options := &redis.Options{
-		Dialer:      dialWithLogging(dialer, logger),
-		Password:    r.Password,
-		DB:          0, // Use default DB,
-		ReadTimeout: r.Options.Timeout,
-		TLSConfig:   tlsConfig,
+	poolSize := r.Options.PoolSize
+	if poolSize == 0 {
+		// go-redis defaults to 10 connections per CPU, but we want at least 32.
+		poolSize = utils.MaxInt(32, 10*runtime.GOMAXPROCS(0))
 	}
 
-	if strings.HasPrefix(r.Host, "/") {
-		options.Network = "unix"
-		options.Addr = r.Host
+	var c *redis.Client
+	if len(r.Sentinels) > 0 {
+		c = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       r.MasterName,
+			SentinelAddrs:    r.Sentinels,
+			SentinelPassword: r.SentinelPassword,
+			Dialer:           sentinelDialer(dialer, logger),
+			Username:         r.Username,
+			Password:         r.Password,
+			DB:               0, // Use default DB,
+			ReadTimeout:      r.Options.Timeout,
+			TLSConfig:        tlsConfig,
+			PoolSize:         poolSize,
+			MinIdleConns:     r.Options.MinIdleConns,
+			PoolTimeout:      r.Options.PoolTimeout,
+			MaxRetries:       poolSize + 1, // https://github.com/go-redis/redis/issues/1737
+		})
 	} else {
-		options.Network = "tcp"
-		options.Addr = net.JoinHostPort(r.Host, fmt.Sprint(r.Port))
+		options := &redis.Options{
+			Dialer:       dialWithLogging(dialer, logger),
+			Username:     r.Username,
+			Password:     r.Password,
+			DB:           0, // Use default DB,
+			ReadTimeout:  r.Options.Timeout,
+			TLSConfig:    tlsConfig,
+			PoolSize:     poolSize,
+			MinIdleConns: r.Options.MinIdleConns,
+			PoolTimeout:  r.Options.PoolTimeout,
+			MaxRetries:   poolSize + 1, // https://github.com/go-redis/redis/issues/1737
+		}
+
+		if strings.HasPrefix(r.Host, "/") {
+			options.Network = "unix"
+			options.Addr = r.Host
+		} else {
+			options.Network = "tcp"
+			options.Addr = net.JoinHostPort(r.Host, fmt.Sprint(r.Port))
+		}
+
+		c = redis.NewClient(options)
 	}
 
-	c := redis.NewClient(options)
-
-	opts := c.Options()
-	opts.PoolSize = utils.MaxInt(32, opts.PoolSize)
-	opts.MaxRetries = opts.PoolSize + 1 // https://github.com/go-redis/redis/issues/1737
-	c = redis.NewClient(opts)
-
 	return icingaredis.NewClient(c, logger, &r.Options), nil
 }
 
@@ -106,9 +144,36 @@ func dialWithLogging(dialer ctxDialerFunc, logger *logging.Logger) ctxDialerFunc
 	}
 }
 
+// sentinelDialer returns a Redis Dialer for redis.FailoverOptions, which go-redis uses to dial the Sentinels,
+// including those it discovers, as well as the master after looking up its address.
+// Unlike dialWithLogging, it dials only once and wraps the error so that go-redis does not retry the command either.
+// Otherwise, a Sentinel that is down would be retried instead of moving on to the next one.
+// If the master can't be reached, the command fails, and the next one looks up the master address again,
+// so that a failover is followed instead of retrying the old master.
+func sentinelDialer(dialer ctxDialerFunc, logger *logging.Logger) ctxDialerFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer(ctx, network, addr)
+		if err != nil {
+			if !utils.IsContextCanceled(err) {
+				logger.Warnw("Can't connect to Redis", zap.String("address", addr), zap.Error(err))
+			}
+
+			return nil, errors.Wrap(err, "can't connect to Redis")
+		}
+
+		return conn, nil
+	}
+}
+
 // Validate checks constraints in the supplied Redis configuration and returns an error if they are violated.
 func (r *Redis) Validate() error {
-	if r.Host == "" {
+	if len(r.Sentinels) > 0 {
+		if r.MasterName == "" {
+			return errors.New("Redis master_name missing, which is required when using Sentinel")
+		}
+	} else if r.MasterName != "" {
+		return errors.New("Redis sentinels missing, which are required when master_name is set")
+	} else if r.Host == "" {
 		return errors.New("Redis host missing")
 	}
 
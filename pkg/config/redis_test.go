@@ -0,0 +1,281 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/creasty/defaults"
+	"github.com/icinga/icingadb/pkg/icingaredis"
+	"github.com/icinga/icingadb/pkg/logging"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRedis_NewClient_Sentinel(t *testing.T) {
+	pong := func(cmd []string) string {
+		if strings.EqualFold(cmd[0], "ping") {
+			return "+PONG\r\n"
+		}
+
+		return "-ERR unknown command\r\n"
+	}
+	master, _ := testRespServer(t, pong)
+
+	t.Run("DownSentinel", func(t *testing.T) {
+		sentinel, _ := testSentinel(t, func(uint64) string { return master }, nil)
+
+		// go-redis shuffles the Sentinels, so the down one is tried first in about half of the runs.
+		for i := 0; i < 4; i++ {
+			c := testRedisClient(t, &Redis{MasterName: "icingadb", Sentinels: []string{testDownAddr(t), sentinel}})
+			require.NoError(t, testRedisPing(t, c))
+		}
+	})
+
+	t.Run("MasterSwitch", func(t *testing.T) {
+		sentinel, _ := testSentinel(t, func(query uint64) string {
+			if query == 1 {
+				// The first lookup still returns the old master, e.g. because the failover is in progress.
+				return testDownAddr(t)
+			}
+
+			return master
+		}, nil)
+
+		c := testRedisClient(t, &Redis{MasterName: "icingadb", Sentinels: []string{sentinel}})
+		require.Error(t, testRedisPing(t, c), "old master should not be retried")
+		require.NoError(t, testRedisPing(t, c), "master address should be looked up again")
+	})
+
+	t.Run("DiscoveredSentinel", func(t *testing.T) {
+		oldMaster, stopOldMaster := testRespServer(t, pong)
+		discovered, _ := testSentinel(t, func(uint64) string { return master }, nil)
+		sentinel, stopSentinel := testSentinel(
+			t, func(uint64) string { return oldMaster }, []string{testDownAddr(t), discovered},
+		)
+
+		c := testRedisClient(t, &Redis{MasterName: "icingadb", Sentinels: []string{sentinel}})
+		require.NoError(t, testRedisPing(t, c))
+
+		// Looking up the master address again now requires the Sentinels discovered via the configured one,
+		// of which the first is down and must not be retried.
+		stopSentinel()
+		stopOldMaster()
+		require.NoError(t, testRedisPing(t, c))
+	})
+}
+
+func TestRedis_NewClient_Options(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		sentinels  []string
+		serverName string
+		want       string
+	}{
+		{name: "Host", host: "redis.example.com", want: "redis.example.com"},
+		{name: "ServerName", host: "192.0.2.1", serverName: "redis.example.com", want: "redis.example.com"},
+		{name: "Sentinel", sentinels: []string{"192.0.2.1:26379"}},
+		{name: "SentinelServerName", sentinels: []string{"192.0.2.1:26379"}, serverName: "redis.example.com", want: "redis.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Redis{}
+			require.NoError(t, defaults.Set(r))
+			r.Host = tt.host
+			r.Sentinels = tt.sentinels
+			if len(tt.sentinels) > 0 {
+				r.MasterName = "icingadb"
+			}
+			r.Username = "icingadb"
+			r.Password = "secret"
+			r.ServerName = tt.serverName
+			r.TlsOptions.Enable = true
+			require.NoError(t, r.Validate())
+
+			c, err := r.NewClient(logging.NewLogger(zap.NewNop().Sugar(), time.Hour))
+			require.NoError(t, err)
+			defer func() { _ = c.Close() }()
+
+			options := c.Client.Options()
+			require.Equal(t, "icingadb", options.Username)
+			require.Equal(t, "secret", options.Password)
+			require.NotNil(t, options.TLSConfig)
+			require.Equal(t, tt.want, options.TLSConfig.ServerName)
+		})
+	}
+}
+
+// testRedisClient returns a client created from r with default options.
+func testRedisClient(t *testing.T, r *Redis) *icingaredis.Client {
+	t.Helper()
+
+	masterName, sentinels := r.MasterName, r.Sentinels
+	require.NoError(t, defaults.Set(r))
+	r.MasterName, r.Sentinels = masterName, sentinels
+
+	c, err := r.NewClient(logging.NewLogger(zap.NewNop().Sugar(), time.Hour))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	return c
+}
+
+// testRedisPing pings Redis using c and fails if that does not complete in time.
+func testRedisPing(t *testing.T, c *icingaredis.Client) error {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := c.Ping(ctx).Err()
+	require.NoError(t, ctx.Err(), "ping should not time out")
+
+	return err
+}
+
+// testSentinel starts a fake Redis Sentinel that answers the query for the master address with master(n)
+// for the n-th query, starting at 1, and the query for the other Sentinels with sentinels.
+// It returns the address of the Sentinel and a function to stop it.
+func testSentinel(t *testing.T, master func(query uint64) string, sentinels []string) (string, func()) {
+	var queries uint64
+
+	return testRespServer(t, func(cmd []string) string {
+		switch {
+		case len(cmd) == 3 && strings.EqualFold(cmd[1], "get-master-addr-by-name"):
+			host, port, err := net.SplitHostPort(master(atomic.AddUint64(&queries, 1)))
+			if err != nil {
+				return "-ERR " + err.Error() + "\r\n"
+			}
+
+			return fmt.Sprintf("*2\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(host), host, len(port), port)
+		case strings.EqualFold(cmd[0], "subscribe"):
+			return fmt.Sprintf("*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:1\r\n", len(cmd[1]), cmd[1])
+		case len(cmd) == 3 && strings.EqualFold(cmd[1], "sentinels"):
+			reply := fmt.Sprintf("*%d\r\n", len(sentinels))
+			for _, sentinel := range sentinels {
+				host, port, err := net.SplitHostPort(sentinel)
+				if err != nil {
+					return "-ERR " + err.Error() + "\r\n"
+				}
+
+				reply += fmt.Sprintf(
+					"*4\r\n$2\r\nip\r\n$%d\r\n%s\r\n$4\r\nport\r\n$%d\r\n%s\r\n", len(host), host, len(port), port,
+				)
+			}
+
+			return reply
+		default:
+			return "-ERR unknown command\r\n"
+		}
+	})
+}
+
+// testDownAddr returns an address nothing listens on.
+func testDownAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+
+	return l.Addr().String()
+}
+
+// testRespServer starts a minimal Redis server that answers each command with the reply returned by handle.
+// It returns the address of the server and a function to stop it, which also closes all its connections.
+func testRespServer(t *testing.T, handle func(cmd []string) string) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	conns := make(map[net.Conn]struct{})
+	stop := func() {
+		_ = l.Close()
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		for conn := range conns {
+			_ = conn.Close()
+		}
+	}
+	t.Cleanup(stop)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			conns[conn] = struct{}{}
+			mu.Unlock()
+
+			go func() {
+				defer func() { _ = conn.Close() }()
+
+				r := bufio.NewReader(conn)
+				for {
+					cmd, err := testReadRespCommand(r)
+					if err != nil {
+						return
+					}
+
+					if _, err := io.WriteString(conn, handle(cmd)); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	return l.Addr().String(), stop
+}
+
+// testReadRespCommand reads a command sent as RESP array of bulk strings.
+func testReadRespCommand(r *bufio.Reader) ([]string, error) {
+	readLine := func(prefix byte) (int, error) {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return 0, err
+		}
+
+		line = strings.TrimSuffix(line, "\r\n")
+		if len(line) < 1 || line[0] != prefix {
+			return 0, errors.Errorf("expected %q, got %q", prefix, line)
+		}
+
+		return strconv.Atoi(line[1:])
+	}
+
+	n, err := readLine('*')
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := make([]string, n)
+	for i := range cmd {
+		size, err := readLine('$')
+		if err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+
+		cmd[i] = string(buf[:size])
+	}
+
+	return cmd, nil
+}
@@ -2,6 +2,7 @@ package icingadb
 
 import (
 	"context"
+	"database/sql"
 	sqlDriver "database/sql/driver"
 	"fmt"
 	"github.com/go-sql-driver/mysql"
@@ -57,6 +58,18 @@ type Options struct {
 	// MaxRowsPerTransaction defines the maximum number of rows per transaction.
 	// The default is 2^13, which in our tests showed the best performance in terms of execution time and parallelism.
 	MaxRowsPerTransaction int `yaml:"max_rows_per_transaction" default:"8192"`
+
+	// StatementComments prefixes each statement built by DB with a comment naming
+	// the table and the operation, e.g. /* icingadb.host.insert */,
+	// so that statements can be identified in slow query logs and the like.
+	// This only applies to the statements built by the Build*Stmt methods, which are used to sync config, state
+	// and history, but not to hand-written ones, e.g. of the schema check, most of high availability and retention.
+	StatementComments bool `yaml:"statement_comments" default:"false"`
+
+	// DisableDeletes prevents Icinga DB from deleting config and state rows that no longer exist in Redis,
+	// e.g. to let a separately reviewed process handle deletions. The rows that would have been deleted
+	// are still calculated and logged. History retention is not affected.
+	DisableDeletes bool `yaml:"disable_deletes" default:"false"`
 }
 
 // Validate checks constraints in the supplied database options and returns an error if they are violated.
@@ -105,23 +118,56 @@ func (db *DB) CheckSchema(ctx context.Context) error {
 	var version uint16
 
 	err := db.QueryRowxContext(ctx, "SELECT version FROM icingadb_schema ORDER BY id DESC LIMIT 1").Scan(&version)
+
+	return checkSchemaVersion(version, expectedDbSchemaVersion, err)
+}
+
+// checkSchemaVersion evaluates the result of querying the database schema version for CheckSchema.
+// err is the error of the query, if any.
+func checkSchemaVersion(version, expected uint16, err error) error {
+	// Since these error messages are trivial and mostly caused by users, we don't need
+	// to print a stack trace here. However, since errors.Errorf() does this automatically,
+	// we need to use fmt instead.
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || isUndefinedTable(err) {
+			return fmt.Errorf("database schema not initialized, please import the database schema of Icinga DB")
+		}
+
 		return errors.Wrap(err, "can't check database schema version")
 	}
 
-	if version != expectedDbSchemaVersion {
-		// Since these error messages are trivial and mostly caused by users, we don't need
-		// to print a stack trace here. However, since errors.Errorf() does this automatically,
-		// we need to use fmt instead.
+	switch {
+	case version < expected:
 		return fmt.Errorf(
 			"unexpected database schema version: v%d (expected v%d), please make sure you have applied all database"+
-				" migrations after upgrading Icinga DB", version, expectedDbSchemaVersion,
+				" migrations after upgrading Icinga DB", version, expected,
+		)
+	case version > expected:
+		return fmt.Errorf(
+			"unexpected database schema version: v%d (expected v%d), the database schema is newer than this"+
+				" version of Icinga DB supports, please upgrade Icinga DB", version, expected,
 		)
 	}
 
 	return nil
 }
 
+// isUndefinedTable checks whether the given error is caused by querying a table that does not exist.
+func isUndefinedTable(err error) bool {
+	var e *mysql.MySQLError
+	if errors.As(err, &e) {
+		// 1146: Table doesn't exist
+		return e.Number == 1146
+	}
+
+	var pe *pq.Error
+	if errors.As(err, &pe) {
+		return pe.Code == "42P01" // undefined_table
+	}
+
+	return false
+}
+
 // BuildColumns returns all columns of the given struct.
 func (db *DB) BuildColumns(subject interface{}) []string {
 	fields := db.Mapper.TypeMap(reflect.TypeOf(subject)).Names
@@ -138,22 +184,25 @@ func (db *DB) BuildColumns(subject interface{}) []string {
 
 // BuildDeleteStmt returns a DELETE statement for the given struct.
 func (db *DB) BuildDeleteStmt(from interface{}) string {
-	return fmt.Sprintf(
+	table := utils.TableName(from)
+
+	return db.tagStmt(table, "delete", fmt.Sprintf(
 		`DELETE FROM "%s" WHERE id IN (?)`,
-		utils.TableName(from),
-	)
+		table,
+	))
 }
 
 // BuildInsertStmt returns an INSERT INTO statement for the given struct.
 func (db *DB) BuildInsertStmt(into interface{}) (string, int) {
+	table := utils.TableName(into)
 	columns := db.BuildColumns(into)
 
-	return fmt.Sprintf(
+	return db.tagStmt(table, "insert", fmt.Sprintf(
 		`INSERT INTO "%s" ("%s") VALUES (%s)`,
-		utils.TableName(into),
+		table,
 		strings.Join(columns, `", "`),
 		fmt.Sprintf(":%s", strings.Join(columns, ", :")),
-	), len(columns)
+	)), len(columns)
 }
 
 // BuildInsertIgnoreStmt returns an INSERT statement for the specified struct for
@@ -171,22 +220,23 @@ func (db *DB) BuildInsertIgnoreStmt(into interface{}) (string, int) {
 		clause = fmt.Sprintf("ON CONFLICT ON CONSTRAINT pk_%s DO NOTHING", table)
 	}
 
-	return fmt.Sprintf(
+	return db.tagStmt(table, "insert_ignore", fmt.Sprintf(
 		`INSERT INTO "%s" ("%s") VALUES (%s) %s`,
 		table,
 		strings.Join(columns, `", "`),
 		fmt.Sprintf(":%s", strings.Join(columns, ", :")),
 		clause,
-	), len(columns)
+	)), len(columns)
 }
 
 // BuildSelectStmt returns a SELECT query that creates the FROM part from the given table struct
 // and the column list from the specified columns struct.
 func (db *DB) BuildSelectStmt(table interface{}, columns interface{}) string {
+	tableName := utils.TableName(table)
 	q := fmt.Sprintf(
 		`SELECT "%s" FROM "%s"`,
 		strings.Join(db.BuildColumns(columns), `", "`),
-		utils.TableName(table),
+		tableName,
 	)
 
 	if scoper, ok := table.(contracts.Scoper); ok {
@@ -194,11 +244,12 @@ func (db *DB) BuildSelectStmt(table interface{}, columns interface{}) string {
 		q += ` WHERE ` + where
 	}
 
-	return q
+	return db.tagStmt(tableName, "select", q)
 }
 
 // BuildUpdateStmt returns an UPDATE statement for the given struct.
 func (db *DB) BuildUpdateStmt(update interface{}) (string, int) {
+	table := utils.TableName(update)
 	columns := db.BuildColumns(update)
 	set := make([]string, 0, len(columns))
 
@@ -206,11 +257,11 @@ func (db *DB) BuildUpdateStmt(update interface{}) (string, int) {
 		set = append(set, fmt.Sprintf(`"%s" = :%s`, col, col))
 	}
 
-	return fmt.Sprintf(
+	return db.tagStmt(table, "update", fmt.Sprintf(
 		`UPDATE "%s" SET %s WHERE id = :id`,
-		utils.TableName(update),
+		table,
 		strings.Join(set, ", "),
-	), len(columns) + 1 // +1 because of WHERE id = :id
+	)), len(columns) + 1 // +1 because of WHERE id = :id
 }
 
 // BuildUpsertStmt returns an upsert statement for the given struct.
@@ -241,14 +292,14 @@ func (db *DB) BuildUpsertStmt(subject interface{}) (stmt string, placeholders in
 		set = append(set, fmt.Sprintf(setFormat, col))
 	}
 
-	return fmt.Sprintf(
+	return db.tagStmt(table, "upsert", fmt.Sprintf(
 		`INSERT INTO "%s" ("%s") VALUES (%s) %s %s`,
 		table,
 		strings.Join(insertColumns, `", "`),
 		fmt.Sprintf(":%s", strings.Join(insertColumns, ",:")),
 		clause,
 		strings.Join(set, ","),
-	), len(insertColumns)
+	)), len(insertColumns)
 }
 
 // BuildWhere returns a WHERE clause with named placeholder conditions built from the specified struct
@@ -263,6 +314,24 @@ func (db *DB) BuildWhere(subject interface{}) (string, int) {
 	return strings.Join(where, ` AND `), len(columns)
 }
 
+// tagStmt prefixes stmt with a comment identifying the table and operation if Options.StatementComments is set.
+// Colons are avoided in the comment on purpose, as they would be interpreted as named placeholders.
+func (db *DB) tagStmt(table, operation, stmt string) string {
+	if !db.Options.StatementComments {
+		return stmt
+	}
+
+	tag := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '.' {
+			return r
+		}
+
+		return -1
+	}, "icingadb."+table+"."+operation)
+
+	return "/* " + tag + " */ " + stmt
+}
+
 // OnSuccess is a callback for successful (bulk) DML operations.
 type OnSuccess[T any] func(ctx context.Context, affectedRows []T) (err error)
 
@@ -688,11 +757,12 @@ func IsRetryable(err error) bool {
 	var e *mysql.MySQLError
 	if errors.As(err, &e) {
 		switch e.Number {
-		case 1053, 1205, 1213, 2006:
+		case 1053, 1205, 1213, 2006, 2013:
 			// 1053: Server shutdown in progress
 			// 1205: Lock wait timeout
 			// 1213: Deadlock found when trying to get lock
 			// 2006: MySQL server has gone away
+			// 2013: Lost connection to MySQL server during query
 			return true
 		default:
 			return false
@@ -0,0 +1,136 @@
+package icingadb
+
+import (
+	"database/sql"
+	sqlDriver "database/sql/driver"
+	"github.com/go-sql-driver/mysql"
+	"github.com/icinga/icingadb/pkg/driver"
+	"github.com/icinga/icingadb/pkg/utils"
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+// testDbEntity has only one column, as the order of multiple columns returned by DB.BuildColumns is not stable.
+type testDbEntity struct {
+	Id string `json:"id"`
+}
+
+func (testDbEntity) TableName() string {
+	return "test_entity"
+}
+
+// newTestDb returns a DB that can be used for statement building only.
+func newTestDb(options *Options) *DB {
+	db := sqlx.NewDb(nil, driver.MySQL)
+	db.Mapper = reflectx.NewMapperFunc("db", func(s string) string {
+		return utils.Key(s, '_')
+	})
+
+	return NewDb(db, nil, options)
+}
+
+func TestDB_StatementComments(t *testing.T) {
+	builders := map[string]func(db *DB) string{
+		"delete": func(db *DB) string { return db.BuildDeleteStmt(testDbEntity{}) },
+		"insert": func(db *DB) string {
+			stmt, _ := db.BuildInsertStmt(testDbEntity{})
+			return stmt
+		},
+		"insert_ignore": func(db *DB) string {
+			stmt, _ := db.BuildInsertIgnoreStmt(testDbEntity{})
+			return stmt
+		},
+		"select": func(db *DB) string { return db.BuildSelectStmt(testDbEntity{}, testDbEntity{}) },
+		"update": func(db *DB) string {
+			stmt, _ := db.BuildUpdateStmt(testDbEntity{})
+			return stmt
+		},
+		"upsert": func(db *DB) string {
+			stmt, _ := db.BuildUpsertStmt(testDbEntity{})
+			return stmt
+		},
+	}
+
+	for operation, build := range builders {
+		t.Run(operation, func(t *testing.T) {
+			untagged := build(newTestDb(&Options{}))
+			require.False(t, strings.HasPrefix(untagged, "/*"), "statements must not be tagged by default")
+
+			tagged := build(newTestDb(&Options{StatementComments: true}))
+			require.Equal(t, "/* icingadb.test_entity."+operation+" */ "+untagged, tagged)
+		})
+	}
+}
+
+func TestDB_tagStmt(t *testing.T) {
+	db := newTestDb(&Options{StatementComments: true})
+
+	require.Equal(
+		t, "/* icingadb.host.insert */ SELECT 1", db.tagStmt("host", "insert", "SELECT 1"),
+	)
+	require.Equal(
+		t, "/* icingadb.hostdroptable.insert */ SELECT 1", db.tagStmt("host */ drop table; /*", "insert", "SELECT 1"),
+		"characters that could terminate the comment or introduce placeholders must be removed",
+	)
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"ErrBadConn", sqlDriver.ErrBadConn, true},
+		{"mysql.ErrInvalidConn", mysql.ErrInvalidConn, true},
+		{"MySQL 1053", &mysql.MySQLError{Number: 1053}, true},
+		{"MySQL 1205", &mysql.MySQLError{Number: 1205}, true},
+		{"MySQL 1213", &mysql.MySQLError{Number: 1213}, true},
+		{"MySQL 2006", &mysql.MySQLError{Number: 2006}, true},
+		{"MySQL 2013", &mysql.MySQLError{Number: 2013}, true},
+		{"MySQL 1062", &mysql.MySQLError{Number: 1062}, false},
+		{"MySQL wrapped", errors.Wrap(&mysql.MySQLError{Number: 1213}, "can't perform query"), true},
+		{"PostgreSQL 40P01", &pq.Error{Code: "40P01"}, true},
+		{"PostgreSQL 53300", &pq.Error{Code: "53300"}, true},
+		{"PostgreSQL 23505", &pq.Error{Code: "23505"}, false},
+		{"other", errors.New("syntax error"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, IsRetryable(tt.err))
+		})
+	}
+}
+
+func TestCheckSchemaVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version uint16
+		err     error
+		want    string
+	}{
+		{"matching", 3, nil, ""},
+		{"older", 2, nil, "unexpected database schema version: v2 (expected v3), please make sure you have applied all"},
+		{"newer", 4, nil, "unexpected database schema version: v4 (expected v3), the database schema is newer"},
+		{"empty", 0, sql.ErrNoRows, "database schema not initialized"},
+		{"missing MySQL", 0, &mysql.MySQLError{Number: 1146}, "database schema not initialized"},
+		{"missing PostgreSQL", 0, &pq.Error{Code: "42P01"}, "database schema not initialized"},
+		{"other", 0, &mysql.MySQLError{Number: 1045}, "can't check database schema version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSchemaVersion(tt.version, 3, tt.err)
+			if tt.want == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.want)
+			}
+		})
+	}
+}
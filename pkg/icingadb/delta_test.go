@@ -174,6 +174,41 @@ func TestDelta(t *testing.T) {
 	})
 }
 
+func TestDelta_ContextCanceled(t *testing.T) {
+	// Neither channel is ever closed, so the delta can only finish because of the canceled context.
+	chActual := make(chan contracts.Entity)
+	chDesired := make(chan contracts.Entity)
+	subject := common.NewSyncSubject(v1.NewEndpoint)
+	logger := logging.NewLogger(zaptest.NewLogger(t).Sugar(), time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	delta := NewDelta(ctx, chActual, chDesired, subject, logger)
+
+	e := new(v1.Endpoint)
+	e.Id = testDeltaMakeIdOrChecksum(1)
+	e.PropertiesChecksum = testDeltaMakeIdOrChecksum(1)
+	chActual <- e
+
+	cancel()
+
+	done := make(chan error)
+	go func() { done <- delta.Wait() }()
+
+	select {
+	case err := <-done:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "Wait should return promptly after the context is canceled")
+	}
+
+	// The done channel is closed once the goroutine calculating the delta has returned.
+	select {
+	case <-delta.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("delta goroutine should have terminated")
+	}
+}
+
 func testDeltaMakeIdOrChecksum(i uint64) types.Binary {
 	b := make([]byte, 20)
 	binary.BigEndian.PutUint64(b, i)
@@ -123,6 +123,10 @@ func (r *RuntimeUpdates) Sync(
 		})
 
 		g.Go(func() error {
+			if r.db.Options.DisableDeletes {
+				return discardDeletes(ctx, s.Name(), deleteIds, deletedFifo, r.logger)
+			}
+
 			var counter com.Counter
 			defer periodic.Start(ctx, r.logger.Interval(), func(_ periodic.Tick) {
 				if count := counter.Reset(); count > 0 {
@@ -291,6 +295,40 @@ func (r *RuntimeUpdates) xRead(ctx context.Context, updateMessagesByKey map[stri
 	}
 }
 
+// discardDeletes consumes IDs from deleteIds without deleting them, as deletes are disabled.
+// Each ID is passed on to deleted, if not nil, so that in-order processing continues.
+func discardDeletes(
+	ctx context.Context, name string, deleteIds <-chan interface{}, deleted chan<- interface{}, logger *logging.Logger,
+) error {
+	var counter com.Counter
+	defer periodic.Start(ctx, logger.Interval(), func(_ periodic.Tick) {
+		if count := counter.Reset(); count > 0 {
+			logger.Warnf("Not deleting %d %s items, as deletes are disabled", count, name)
+		}
+	}).Stop()
+
+	for {
+		select {
+		case id, ok := <-deleteIds:
+			if !ok {
+				return nil
+			}
+
+			counter.Inc()
+
+			if deleted != nil {
+				select {
+				case deleted <- id:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
 // structifyStream gets Redis stream messages (redis.XMessage) via the updateMessages channel and converts
 // those messages into Icinga DB entities (contracts.Entity) using the provided structifier.
 // Converted entities are inserted into the upsertEntities or deleteIds channel depending on the "runtime_type" message field.
@@ -10,6 +10,7 @@ import (
 	"github.com/icinga/icingadb/pkg/icingaredis"
 	"github.com/icinga/icingadb/pkg/icingaredis/telemetry"
 	"github.com/icinga/icingadb/pkg/logging"
+	"github.com/icinga/icingadb/pkg/metrics"
 	"github.com/icinga/icingadb/pkg/utils"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
@@ -20,20 +21,55 @@ import (
 
 // Sync implements a rendezvous point for Icinga DB and Redis to synchronize their entities.
 type Sync struct {
-	db     *DB
-	redis  *icingaredis.Client
-	logger *logging.Logger
+	db           *DB
+	redis        *icingaredis.Client
+	logger       *logging.Logger
+	idSampleSize int
+	summary      *syncSummarizer
 }
 
 // NewSync returns a new Sync.
-func NewSync(db *DB, redis *icingaredis.Client, logger *logging.Logger) *Sync {
+// idSampleSize limits the number of IDs logged at debug level for each insert, update and delete.
+func NewSync(db *DB, redis *icingaredis.Client, logger *logging.Logger, idSampleSize int) *Sync {
 	return &Sync{
-		db:     db,
-		redis:  redis,
-		logger: logger,
+		db:           db,
+		redis:        redis,
+		logger:       logger,
+		idSampleSize: idSampleSize,
+		summary:      &syncSummarizer{},
 	}
 }
 
+// StartSummary starts a new SyncSummary, discarding the results collected so far.
+// It should be called at the beginning of each sync cycle.
+func (s Sync) StartSummary() {
+	s.summary.reset()
+}
+
+// Summary returns the results of all ApplyDelta calls since the last call to StartSummary.
+func (s Sync) Summary() SyncSummary {
+	return s.summary.get()
+}
+
+// LogSummary logs the current SyncSummary, at info level if the sync cycle finished
+// and at warn level if it was aborted.
+func (s Sync) LogSummary(aborted bool) {
+	summary := s.Summary()
+
+	logFn, msg := s.logger.Infow, "Finished sync cycle"
+	if aborted {
+		logFn, msg = s.logger.Warnw, "Aborted sync cycle"
+	}
+
+	logFn(msg,
+		zap.Int("created", summary.Created),
+		zap.Int("updated", summary.Updated),
+		zap.Int("deleted", summary.Deleted),
+		zap.Int("skipped_deletes", summary.SkippedDeletes),
+		zap.Strings("failed", summary.Failed),
+		zap.Duration("took", time.Since(summary.Start)))
+}
+
 // SyncAfterDump waits for a config dump to finish (using the dump parameter) and then starts a sync for the given
 // sync subject using the Sync function.
 func (s Sync) SyncAfterDump(ctx context.Context, subject *common.SyncSubject, dump *DumpSignals) error {
@@ -45,6 +81,14 @@ func (s Sync) SyncAfterDump(ctx context.Context, subject *common.SyncSubject, du
 	defer logTicker.Stop()
 	loggedWaiting := false
 
+	// A nil channel blocks forever, so without a configured timeout we wait indefinitely.
+	var timeout <-chan time.Time
+	if d := s.redis.Options.DumpTimeout; d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
 	for {
 		select {
 		case <-logTicker.C:
@@ -63,6 +107,13 @@ func (s Sync) SyncAfterDump(ctx context.Context, subject *common.SyncSubject, du
 				zap.String("key", key),
 				zap.Duration("waited", time.Since(startTime)))
 			return s.Sync(ctx, subject)
+		case <-timeout:
+			err := errors.Errorf(
+				"no dump done signal received for %s after %s. Make sure that Icinga 2 is running and writes to Redis",
+				key, time.Since(startTime))
+			s.summary.fail(subject, err)
+
+			return err
 		case <-ctx.Done():
 			return ctx.Err()
 		}
@@ -71,12 +122,27 @@ func (s Sync) SyncAfterDump(ctx context.Context, subject *common.SyncSubject, du
 
 // Sync synchronizes entities between Icinga DB and Redis created with the specified sync subject.
 // This function does not respect dump signals. For this, use SyncAfterDump.
-func (s Sync) Sync(ctx context.Context, subject *common.SyncSubject) error {
+// A failure is added to the current SyncSummary.
+func (s Sync) Sync(ctx context.Context, subject *common.SyncSubject) (err error) {
+	defer func() { s.summary.fail(subject, err) }()
+
+	typeName := utils.Key(utils.Name(subject.Entity()), '_')
+	start := time.Now()
+	defer func() { metrics.SyncDuration.WithLabelValues(typeName).Observe(time.Since(start).Seconds()) }()
+
 	g, ctx := errgroup.WithContext(ctx)
 
 	desired, redisErrs := s.redis.YieldAll(ctx, subject)
 	// Let errors from Redis cancel our group.
-	com.ErrgroupReceive(g, redisErrs)
+	g.Go(func() error {
+		if err := <-redisErrs; err != nil {
+			return err
+		}
+
+		metrics.SyncRedisDuration.WithLabelValues(typeName).Observe(time.Since(start).Seconds())
+
+		return nil
+	})
 
 	e, ok := v1.EnvironmentFromContext(ctx)
 	if !ok {
@@ -98,17 +164,36 @@ func (s Sync) Sync(ctx context.Context, subject *common.SyncSubject) error {
 }
 
 // ApplyDelta applies all changes from Delta to the database.
-func (s Sync) ApplyDelta(ctx context.Context, delta *Delta) error {
+// The changes are added to the current SyncSummary if they have been applied successfully.
+func (s Sync) ApplyDelta(ctx context.Context, delta *Delta) (err error) {
+	defer func() { s.summary.add(delta, s.db.Options.DisableDeletes, err) }()
+
 	if err := delta.Wait(); err != nil {
 		return errors.Wrap(err, "can't calculate delta")
 	}
 
+	typeName := utils.Key(utils.Name(delta.Subject.Entity()), '_')
+	start := time.Now()
+	defer func() {
+		if err == nil {
+			metrics.SyncApplyDuration.WithLabelValues(typeName).Observe(time.Since(start).Seconds())
+			metrics.SyncRows.WithLabelValues(typeName, "create").Add(float64(len(delta.Create)))
+			metrics.SyncRows.WithLabelValues(typeName, "update").Add(float64(len(delta.Update)))
+			if s.db.Options.DisableDeletes {
+				metrics.SyncRows.WithLabelValues(typeName, "skip_delete").Add(float64(len(delta.Delete)))
+			} else {
+				metrics.SyncRows.WithLabelValues(typeName, "delete").Add(float64(len(delta.Delete)))
+			}
+		}
+	}()
+
 	g, ctx := errgroup.WithContext(ctx)
 	stat := getCounterForEntity(delta.Subject.Entity())
 
 	// Create
 	if len(delta.Create) > 0 {
 		s.logger.Infof("Inserting %d items of type %s", len(delta.Create), utils.Key(utils.Name(delta.Subject.Entity()), ' '))
+		s.logIdSample("insert", delta.Subject, delta.Create)
 		var entities <-chan contracts.Entity
 		if delta.Subject.WithChecksum() {
 			pairs, errs := s.redis.HMYield(
@@ -136,6 +221,7 @@ func (s Sync) ApplyDelta(ctx context.Context, delta *Delta) error {
 	// Update
 	if len(delta.Update) > 0 {
 		s.logger.Infof("Updating %d items of type %s", len(delta.Update), utils.Key(utils.Name(delta.Subject.Entity()), ' '))
+		s.logIdSample("update", delta.Subject, delta.Update)
 		pairs, errs := s.redis.HMYield(
 			ctx,
 			fmt.Sprintf("icinga:%s", utils.Key(utils.Name(delta.Subject.Entity()), ':')),
@@ -158,8 +244,13 @@ func (s Sync) ApplyDelta(ctx context.Context, delta *Delta) error {
 	}
 
 	// Delete
-	if len(delta.Delete) > 0 {
+	if len(delta.Delete) > 0 && s.db.Options.DisableDeletes {
+		s.logger.Warnf("Not deleting %d items of type %s, as deletes are disabled",
+			len(delta.Delete), utils.Key(utils.Name(delta.Subject.Entity()), ' '))
+		s.logIdSample("skip_delete", delta.Subject, delta.Delete)
+	} else if len(delta.Delete) > 0 {
 		s.logger.Infof("Deleting %d items of type %s", len(delta.Delete), utils.Key(utils.Name(delta.Subject.Entity()), ' '))
+		s.logIdSample("delete", delta.Subject, delta.Delete)
 		g.Go(func() error {
 			return s.db.Delete(ctx, delta.Subject.Entity(), delta.Delete.IDs(), OnSuccessIncrement[any](stat))
 		})
@@ -168,8 +259,42 @@ func (s Sync) ApplyDelta(ctx context.Context, delta *Delta) error {
 	return g.Wait()
 }
 
+// logIdSample logs up to idSampleSize IDs of the entities affected by the given operation at debug level.
+// The IDs are logged in their hex representation, as used for the keys of EntitiesById.
+func (s Sync) logIdSample(operation string, subject *common.SyncSubject, entities EntitiesById) {
+	if s.idSampleSize < 1 || !s.logger.Desugar().Core().Enabled(zap.DebugLevel) {
+		return
+	}
+
+	size := s.idSampleSize
+	if len(entities) < size {
+		size = len(entities)
+	}
+
+	sample := make([]string, 0, size)
+	for id := range entities {
+		if len(sample) == size {
+			break
+		}
+
+		sample = append(sample, id)
+	}
+
+	s.logger.Debugw("Sample of affected IDs",
+		zap.String("type", utils.Key(utils.Name(subject.Entity()), ' ')),
+		zap.String("operation", operation),
+		zap.Int("total", len(entities)),
+		zap.Strings("ids", sample))
+}
+
 // SyncCustomvars synchronizes customvar and customvar_flat.
-func (s Sync) SyncCustomvars(ctx context.Context) error {
+// A failure is added to the current SyncSummary as failure of customvar.
+func (s Sync) SyncCustomvars(ctx context.Context) (err error) {
+	defer func() { s.summary.fail(common.NewSyncSubject(v1.NewCustomvar), err) }()
+
+	start := time.Now()
+	defer func() { metrics.SyncDuration.WithLabelValues("customvar").Observe(time.Since(start).Seconds()) }()
+
 	e, ok := v1.EnvironmentFromContext(ctx)
 	if !ok {
 		return errors.New("can't get environment from context")
@@ -0,0 +1,80 @@
+package icingadb
+
+import (
+	"github.com/icinga/icingadb/pkg/common"
+	"github.com/icinga/icingadb/pkg/utils"
+	"sync"
+	"time"
+)
+
+// SyncSummary aggregates the results of all types synchronized in a sync cycle.
+type SyncSummary struct {
+	// Start is the time the sync cycle started.
+	Start time.Time
+	// Created, Updated and Deleted are the number of rows changed across all types.
+	Created int
+	Updated int
+	Deleted int
+	// SkippedDeletes is the number of rows that were not deleted because deletes are disabled.
+	SkippedDeletes int
+	// Failed lists the types whose sync failed.
+	Failed []string
+}
+
+// syncSummarizer collects the results of a sync cycle into a SyncSummary.
+// It is safe for concurrent use.
+type syncSummarizer struct {
+	mu      sync.Mutex
+	summary SyncSummary
+}
+
+// reset starts a new summary.
+func (s *syncSummarizer) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.summary = SyncSummary{Start: time.Now()}
+}
+
+// add adds the changes of the specified delta to the summary if it has been applied successfully.
+func (s *syncSummarizer) add(delta *Delta, deletesDisabled bool, err error) {
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.summary.Created += len(delta.Create)
+	s.summary.Updated += len(delta.Update)
+	if deletesDisabled {
+		s.summary.SkippedDeletes += len(delta.Delete)
+	} else {
+		s.summary.Deleted += len(delta.Delete)
+	}
+}
+
+// fail adds the specified type to the failed types of the summary if err is not nil.
+// Errors from canceled contexts are ignored, as they don't indicate a failure of the type itself,
+// but e.g. that another type failed and canceled the sync.
+func (s *syncSummarizer) fail(subject *common.SyncSubject, err error) {
+	if err == nil || utils.IsContextCanceled(err) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.summary.Failed = append(s.summary.Failed, utils.Key(subject.Name(), ' '))
+}
+
+// get returns a copy of the current summary.
+func (s *syncSummarizer) get() SyncSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	summary := s.summary
+	summary.Failed = append([]string(nil), s.summary.Failed...)
+
+	return summary
+}
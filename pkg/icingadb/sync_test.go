@@ -0,0 +1,177 @@
+package icingadb
+
+import (
+	"context"
+	"github.com/icinga/icingadb/pkg/common"
+	"github.com/icinga/icingadb/pkg/contracts"
+	v1 "github.com/icinga/icingadb/pkg/icingadb/v1"
+	"github.com/icinga/icingadb/pkg/icingaredis"
+	"github.com/icinga/icingadb/pkg/logging"
+	"github.com/icinga/icingadb/pkg/metrics"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"testing"
+	"time"
+)
+
+func TestSync_SyncAfterDump_Timeout(t *testing.T) {
+	logger := logging.NewLogger(zap.NewNop().Sugar(), time.Hour)
+	rc := icingaredis.NewClient(nil, logger, &icingaredis.Options{DumpTimeout: 50 * time.Millisecond})
+	s := NewSync(nil, rc, logger, 0)
+
+	// The dump signals are never listened to, so no done signal will ever arrive.
+	dump := NewDumpSignals(rc, logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := s.SyncAfterDump(ctx, common.NewSyncSubject(v1.NewHost), dump)
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no dump done signal received for icinga:host")
+	require.NoError(t, ctx.Err(), "SyncAfterDump should return before the context is done")
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestSync_logIdSample(t *testing.T) {
+	entities := EntitiesById{}
+	for i := uint64(1); i <= 5; i++ {
+		e := new(v1.Endpoint)
+		e.Id = testDeltaMakeIdOrChecksum(i)
+		entities[e.ID().String()] = e
+	}
+
+	t.Run("Sampled", func(t *testing.T) {
+		core, logs := observer.New(zapcore.DebugLevel)
+		s := NewSync(nil, nil, logging.NewLogger(zap.New(core).Sugar(), time.Hour), 3)
+
+		s.logIdSample("delete", common.NewSyncSubject(v1.NewEndpoint), entities)
+
+		require.Equal(t, 1, logs.Len())
+		fields := logs.All()[0].ContextMap()
+		require.Equal(t, "delete", fields["operation"])
+		require.Equal(t, "endpoint", fields["type"])
+		require.EqualValues(t, 5, fields["total"])
+
+		ids := fields["ids"].([]interface{})
+		require.Len(t, ids, 3)
+		for _, id := range ids {
+			require.Contains(t, entities, id)
+			require.Regexp(t, "^[0-9a-f]{40}$", id)
+		}
+	})
+
+	t.Run("DebugDisabled", func(t *testing.T) {
+		core, logs := observer.New(zapcore.InfoLevel)
+		s := NewSync(nil, nil, logging.NewLogger(zap.New(core).Sugar(), time.Hour), 3)
+
+		s.logIdSample("delete", common.NewSyncSubject(v1.NewEndpoint), entities)
+
+		require.Equal(t, 0, logs.Len())
+	})
+
+	t.Run("SampleSizeZero", func(t *testing.T) {
+		core, logs := observer.New(zapcore.DebugLevel)
+		s := NewSync(nil, nil, logging.NewLogger(zap.New(core).Sugar(), time.Hour), 0)
+
+		s.logIdSample("delete", common.NewSyncSubject(v1.NewEndpoint), entities)
+
+		require.Equal(t, 0, logs.Len())
+	})
+}
+
+func TestSync_Summary(t *testing.T) {
+	s := NewSync(nil, nil, logging.NewLogger(zap.NewNop().Sugar(), time.Hour), 0)
+	s.StartSummary()
+
+	s.summary.add(testSyncMakeDelta(v1.NewHost, 3, 2, 1), false, nil)
+	s.summary.add(testSyncMakeDelta(v1.NewService, 10, 0, 5), false, nil)
+	s.summary.add(testSyncMakeDelta(v1.NewEndpoint, 7, 7, 7), false, errors.New("boom"))
+	s.summary.fail(common.NewSyncSubject(v1.NewEndpoint), errors.New("boom"))
+	s.summary.fail(common.NewSyncSubject(v1.NewZone), context.Canceled)
+	s.summary.fail(common.NewSyncSubject(v1.NewUser), nil)
+
+	summary := s.Summary()
+	require.Equal(t, 13, summary.Created)
+	require.Equal(t, 2, summary.Updated)
+	require.Equal(t, 6, summary.Deleted)
+	require.Equal(t, []string{"endpoint"}, summary.Failed)
+	require.False(t, summary.Start.IsZero())
+
+	s.StartSummary()
+	require.Equal(t, SyncSummary{Start: s.Summary().Start}, s.Summary())
+}
+
+func TestSync_LogSummary_Aborted(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := logging.NewLogger(zap.New(core).Sugar(), time.Hour)
+	rc := icingaredis.NewClient(nil, logger, &icingaredis.Options{DumpTimeout: time.Millisecond})
+	s := NewSync(nil, rc, logger, 0)
+	s.StartSummary()
+
+	err := s.SyncAfterDump(context.Background(), common.NewSyncSubject(v1.NewHost), NewDumpSignals(rc, logger))
+	require.Error(t, err)
+
+	s.LogSummary(true)
+
+	entries := logs.FilterMessage("Aborted sync cycle").All()
+	require.Len(t, entries, 1)
+	require.Equal(t, zapcore.WarnLevel, entries[0].Level)
+	require.Equal(t, []interface{}{"host"}, entries[0].ContextMap()["failed"])
+}
+
+func TestSync_ApplyDelta_DisableDeletes(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	// The DB has no connection, so any statement actually executed would fail.
+	s := NewSync(newTestDb(&Options{DisableDeletes: true}), nil, logging.NewLogger(zap.New(core).Sugar(), time.Hour), 0)
+	s.StartSummary()
+
+	// The metrics are global, so compare against their values before this test instead of absolute values.
+	skipped := metrics.SyncRows.WithLabelValues("endpoint", "skip_delete")
+	deleted := metrics.SyncRows.WithLabelValues("endpoint", "delete")
+	skippedBefore, deletedBefore := skipped.Value(), deleted.Value()
+
+	delta := testSyncMakeDelta(v1.NewEndpoint, 0, 0, 3)
+	require.NoError(t, s.ApplyDelta(context.Background(), delta))
+
+	require.Len(t, delta.Delete, 3, "deletes should still be calculated")
+	require.Equal(t, 1, logs.FilterMessage("Not deleting 3 items of type endpoint, as deletes are disabled").Len())
+
+	summary := s.Summary()
+	require.Equal(t, 0, summary.Deleted)
+	require.Equal(t, 3, summary.SkippedDeletes)
+
+	require.Equal(t, skippedBefore+3, skipped.Value())
+	require.Equal(t, deletedBefore, deleted.Value())
+}
+
+// testSyncMakeDelta returns a calculated Delta with the specified number of entities to create, update and delete.
+func testSyncMakeDelta(factory contracts.EntityFactoryFunc, create, update, del int) *Delta {
+	delta := &Delta{
+		Create:  EntitiesById{},
+		Update:  EntitiesById{},
+		Delete:  EntitiesById{},
+		Subject: common.NewSyncSubject(factory),
+		done:    make(chan error),
+	}
+	close(delta.done)
+
+	var id uint64
+	for _, n := range []struct {
+		ebi   EntitiesById
+		count int
+	}{{delta.Create, create}, {delta.Update, update}, {delta.Delete, del}} {
+		for i := 0; i < n.count; i++ {
+			id++
+			e := new(v1.Endpoint)
+			e.Id = testDeltaMakeIdOrChecksum(id)
+			n.ebi[e.ID().String()] = e
+		}
+	}
+
+	return delta
+}
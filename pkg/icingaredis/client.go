@@ -3,13 +3,16 @@ package icingaredis
 import (
 	"context"
 	"github.com/go-redis/redis/v8"
+	"github.com/icinga/icingadb/pkg/backoff"
 	"github.com/icinga/icingadb/pkg/com"
 	"github.com/icinga/icingadb/pkg/common"
 	"github.com/icinga/icingadb/pkg/contracts"
 	"github.com/icinga/icingadb/pkg/logging"
 	"github.com/icinga/icingadb/pkg/periodic"
+	"github.com/icinga/icingadb/pkg/retry"
 	"github.com/icinga/icingadb/pkg/utils"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/sync/semaphore"
 	"runtime"
@@ -24,16 +27,30 @@ type Client struct {
 	Options *Options
 
 	logger *logging.Logger
+
+	// hmyieldSem limits the number of concurrent HMYield pipelines across all callers.
+	// Nil if not limited.
+	hmyieldSem *semaphore.Weighted
 }
 
 // Options define user configurable Redis options.
 type Options struct {
-	BlockTimeout        time.Duration `yaml:"block_timeout"         default:"1s"`
-	HMGetCount          int           `yaml:"hmget_count"           default:"4096"`
-	HScanCount          int           `yaml:"hscan_count"           default:"4096"`
-	MaxHMGetConnections int           `yaml:"max_hmget_connections" default:"8"`
-	Timeout             time.Duration `yaml:"timeout"               default:"30s"`
-	XReadCount          int           `yaml:"xread_count"           default:"4096"`
+	BlockTimeout         time.Duration `yaml:"block_timeout"          default:"1s"`
+	CheckTTL             bool          `yaml:"check_ttl"              default:"false"`
+	CommandRetries       int           `yaml:"command_retries"        default:"5"`
+	CommandRetryBackoff  time.Duration `yaml:"command_retry_backoff"  default:"5s"`
+	DumpTimeout          time.Duration `yaml:"dump_timeout"           default:"0s"`
+	HMGetCount           int           `yaml:"hmget_count"            default:"4096"`
+	HScanCount           int           `yaml:"hscan_count"            default:"4096"`
+	MaxHMGetConnections  int           `yaml:"max_hmget_connections"  default:"8"`
+	MaxHMYieldPipelines  int           `yaml:"max_hmyield_pipelines"  default:"-1"`
+	MinIdleConns         int           `yaml:"min_idle_conns"         default:"0"`
+	PoolSize             int           `yaml:"pool_size"              default:"0"`
+	PoolTimeout          time.Duration `yaml:"pool_timeout"           default:"0s"`
+	Timeout              time.Duration `yaml:"timeout"                default:"30s"`
+	VerifyHScan          bool          `yaml:"verify_hscan"           default:"false"`
+	VerifyHScanTolerance float64       `yaml:"verify_hscan_tolerance" default:"0.01"`
+	XReadCount           int           `yaml:"xread_count"            default:"4096"`
 }
 
 // Validate checks constraints in the supplied Redis options and returns an error if they are violated.
@@ -41,6 +58,15 @@ func (o *Options) Validate() error {
 	if o.BlockTimeout <= 0 {
 		return errors.New("block_timeout must be positive")
 	}
+	if o.CommandRetries < 0 {
+		return errors.New("command_retries cannot be negative")
+	}
+	if o.CommandRetryBackoff <= time.Millisecond {
+		return errors.New("command_retry_backoff must be greater than 1ms")
+	}
+	if o.DumpTimeout < 0 {
+		return errors.New("dump_timeout cannot be negative. Configure a positive value, or use 0 to wait indefinitely")
+	}
 	if o.HMGetCount < 1 {
 		return errors.New("hmget_count must be at least 1")
 	}
@@ -50,9 +76,24 @@ func (o *Options) Validate() error {
 	if o.MaxHMGetConnections < 1 {
 		return errors.New("max_hmget_connections must be at least 1")
 	}
+	if o.MaxHMYieldPipelines == 0 {
+		return errors.New("max_hmyield_pipelines cannot be 0. Configure a value greater than zero, or use -1 for no limit")
+	}
+	if o.MinIdleConns < 0 {
+		return errors.New("min_idle_conns cannot be negative")
+	}
+	if o.PoolSize < 0 {
+		return errors.New("pool_size cannot be negative. Configure a positive value, or use 0 for the default")
+	}
+	if o.PoolTimeout < 0 {
+		return errors.New("pool_timeout cannot be negative. Configure a positive value, or use 0 for the default")
+	}
 	if o.Timeout == 0 {
 		return errors.New("timeout cannot be 0. Configure a value greater than zero, or use -1 for no timeout")
 	}
+	if o.VerifyHScanTolerance < 0 {
+		return errors.New("verify_hscan_tolerance cannot be negative")
+	}
 	if o.XReadCount < 1 {
 		return errors.New("xread_count must be at least 1")
 	}
@@ -62,7 +103,12 @@ func (o *Options) Validate() error {
 
 // NewClient returns a new icingaredis.Client wrapper for a pre-existing *redis.Client.
 func NewClient(client *redis.Client, logger *logging.Logger, options *Options) *Client {
-	return &Client{Client: client, logger: logger, Options: options}
+	c := &Client{Client: client, logger: logger, Options: options}
+	if options.MaxHMYieldPipelines > 0 {
+		c.hmyieldSem = semaphore.NewWeighted(int64(options.MaxHMYieldPipelines))
+	}
+
+	return c
 }
 
 // HPair defines Redis hashes field-value pairs.
@@ -87,13 +133,24 @@ func (c *Client) HYield(ctx context.Context, key string) (<-chan HPair, <-chan e
 		var page []string
 
 		for {
-			cmd := c.HScan(ctx, key, cursor, "", int64(c.Options.HScanCount))
-			page, cursor, err = cmd.Result()
+			var next uint64
+			err = c.retryTransient(ctx, func(ctx context.Context) error {
+				cmd := c.HScan(ctx, key, cursor, "", int64(c.Options.HScanCount))
+
+				var err error
+				page, next, err = cmd.Result()
+				if err != nil {
+					return WrapCmdErr(cmd)
+				}
 
+				return nil
+			})
 			if err != nil {
-				return WrapCmdErr(cmd)
+				return err
 			}
 
+			cursor = next
+
 			for i := 0; i < len(page); i += 2 {
 				if _, ok := seen[page[i]]; ok {
 					// Ignore duplicate returned by HSCAN.
@@ -118,15 +175,62 @@ func (c *Client) HYield(ctx context.Context, key string) (<-chan HPair, <-chan e
 			}
 		}
 
+		if c.Options.VerifyHScan {
+			c.verifyHLen(ctx, key, len(seen))
+		}
+
 		return nil
 	}))
 }
 
+// verifyHLen compares the number of fields of the hash stored at key with the number of unique fields
+// returned by a completed HSCAN, which may reveal scan anomalies, e.g. fields missed during a rehash.
+// As Icinga 2 keeps writing runtime updates to the hash while it is being scanned, small differences are expected.
+// Therefore, a warning is only logged if the difference exceeds Options.VerifyHScanTolerance as fraction of HLEN,
+// otherwise it is logged at debug level.
+func (c *Client) verifyHLen(ctx context.Context, key string, scanned int) {
+	cmd := c.HLen(ctx, key)
+	hlen, err := cmd.Result()
+	if err != nil {
+		if !utils.IsContextCanceled(err) {
+			c.logger.Debugw("Can't verify number of scanned fields", zap.Error(WrapCmdErr(cmd)))
+		}
+
+		return
+	}
+
+	diff := hlen - int64(scanned)
+	if diff == 0 {
+		return
+	}
+	if diff < 0 {
+		diff = -diff
+	}
+
+	logFn := c.logger.Debugw
+	if float64(diff) > c.Options.VerifyHScanTolerance*float64(hlen) {
+		logFn = c.logger.Warnw
+	}
+
+	logFn("Number of fields returned by HSCAN differs from HLEN",
+		zap.String("key", key), zap.Int("scanned", scanned), zap.Int64("hlen", hlen))
+}
+
 // HMYield yields HPair field-value pairs for the specified fields in the hash stored at key.
+// At most Options.MaxHMYieldPipelines calls run at the same time across the client, further calls wait for a free slot.
 func (c *Client) HMYield(ctx context.Context, key string, fields ...string) (<-chan HPair, <-chan error) {
 	pairs := make(chan HPair)
 
 	return pairs, com.WaitAsync(contracts.WaiterFunc(func() error {
+		if c.hmyieldSem != nil {
+			if err := c.hmyieldSem.Acquire(ctx, 1); err != nil {
+				close(pairs)
+
+				return errors.Wrap(err, "can't acquire semaphore")
+			}
+			defer c.hmyieldSem.Release(1)
+		}
+
 		var counter com.Counter
 		defer c.log(ctx, key, &counter).Stop()
 
@@ -154,11 +258,20 @@ func (c *Client) HMYield(ctx context.Context, key string, fields ...string) (<-c
 			g.Go(func() error {
 				defer sem.Release(1)
 
-				cmd := c.HMGet(ctx, key, batch...)
-				vals, err := cmd.Result()
+				var vals []interface{}
+				err := c.retryTransient(ctx, func(ctx context.Context) error {
+					cmd := c.HMGet(ctx, key, batch...)
+
+					var err error
+					vals, err = cmd.Result()
+					if err != nil {
+						return WrapCmdErr(cmd)
+					}
 
+					return nil
+				})
 				if err != nil {
-					return WrapCmdErr(cmd)
+					return err
 				}
 
 				for i, v := range vals {
@@ -217,6 +330,10 @@ func (c Client) YieldAll(ctx context.Context, subject *common.SyncSubject) (<-ch
 		key = "icinga:" + key
 	}
 
+	if c.Options.CheckTTL {
+		c.warnOnTTL(ctx, key)
+	}
+
 	pairs, errs := c.HYield(ctx, key)
 	g, ctx := errgroup.WithContext(ctx)
 	// Let errors from HYield cancel the group.
@@ -229,6 +346,72 @@ func (c Client) YieldAll(ctx context.Context, subject *common.SyncSubject) (<-ch
 	return desired, com.WaitAsync(g)
 }
 
+// warnOnTTL logs a warning if the specified key has a TTL set. It is enabled by Options.CheckTTL,
+// as it costs an additional round trip per key and only helps to diagnose a misconfigured Redis.
+// Icinga 2 writes its objects without expiration, so a TTL indicates a misconfigured Redis or
+// another application sharing it, which can cause keys to vanish in the middle of a sync.
+func (c *Client) warnOnTTL(ctx context.Context, key string) {
+	cmd := c.TTL(ctx, key)
+	ttl, err := cmd.Result()
+	if err != nil {
+		if !utils.IsContextCanceled(err) {
+			c.logger.Debugw("Can't check TTL", zap.Error(WrapCmdErr(cmd)))
+		}
+
+		return
+	}
+
+	// TTL returns negative values if the key does not exist or has no expiration.
+	if ttl > 0 {
+		c.logger.Warnw("Redis key has a TTL set and will expire, Icinga DB may see incomplete data",
+			zap.String("key", key), zap.Duration("ttl", ttl))
+	}
+}
+
+// retryTransient calls fn and retries it with backoff up to Options.CommandRetries times
+// as long as it fails with an error that satisfies IsTransientError.
+// This is different from reconnecting, which is already handled when dialing.
+// Errors that are not transient are returned unchanged.
+func (c *Client) retryTransient(ctx context.Context, fn retry.RetryableFunc) error {
+	var attempts int
+	var lastErr error
+
+	err := retry.WithBackoff(
+		ctx,
+		func(ctx context.Context) error {
+			attempts++
+			lastErr = fn(ctx)
+
+			return lastErr
+		},
+		func(err error) bool {
+			return attempts <= c.Options.CommandRetries && IsTransientError(err)
+		},
+		backoff.NewExponentialWithJitter(1*time.Millisecond, c.Options.CommandRetryBackoff),
+		retry.Settings{
+			OnError: func(_ time.Duration, attempt uint64, err, _ error) {
+				// As the number of retries is limited, each one is logged, not only those with a new error.
+				if attempts <= c.Options.CommandRetries && IsTransientError(err) {
+					c.logger.Warnw("Redis command failed with a transient error. Retrying",
+						zap.Error(err), zap.Uint64("attempt", attempt+1), zap.Int("max_retries", c.Options.CommandRetries))
+				}
+			},
+		},
+	)
+	if err != nil && lastErr != nil {
+		if !IsTransientError(lastErr) {
+			// Don't let retry.WithBackoff claim that an error that has never been retried can't be retried.
+			return lastErr
+		}
+
+		if !utils.IsContextCanceled(err) {
+			return errors.Wrapf(err, "Redis command failed after %d attempts", attempts)
+		}
+	}
+
+	return err
+}
+
 func (c *Client) log(ctx context.Context, key string, counter *com.Counter) periodic.Stopper {
 	return periodic.Start(ctx, c.logger.Interval(), func(tick periodic.Tick) {
 		// We may never get to progress logging here,
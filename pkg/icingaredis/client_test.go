@@ -0,0 +1,360 @@
+package icingaredis
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/icinga/icingadb/pkg/common"
+	v1 "github.com/icinga/icingadb/pkg/icingadb/v1"
+	"github.com/icinga/icingadb/pkg/logging"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"golang.org/x/sync/errgroup"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// errHooked is returned by hmgetHook to prevent the command from being sent to a Redis server.
+var errHooked = errors.New("hooked")
+
+// testRedisError is an error reply as returned by Redis.
+type testRedisError string
+
+func (e testRedisError) Error() string { return string(e) }
+
+func (testRedisError) RedisError() {}
+
+// hmgetHook answers HMGET commands itself and records the maximum number of concurrently processed commands.
+// The first failures commands are answered with failure, or a LOADING error if not set.
+type hmgetHook struct {
+	mu       sync.Mutex
+	current  int
+	max      int
+	calls    int
+	failures int
+	failure  error
+}
+
+func (h *hmgetHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	h.mu.Lock()
+	h.current++
+	if h.current > h.max {
+		h.max = h.current
+	}
+	h.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	return ctx, errHooked
+}
+
+func (h *hmgetHook) AfterProcess(_ context.Context, cmd redis.Cmder) error {
+	h.mu.Lock()
+	h.current--
+	h.calls++
+	fail := h.calls <= h.failures
+	h.mu.Unlock()
+
+	if fail {
+		if h.failure != nil {
+			cmd.SetErr(h.failure)
+		} else {
+			cmd.SetErr(testRedisError("LOADING Redis is loading the dataset in memory"))
+		}
+
+		return nil
+	}
+
+	if c, ok := cmd.(*redis.SliceCmd); ok && errors.Is(c.Err(), errHooked) {
+		// HMGET key field [field ...]
+		vals := make([]interface{}, len(c.Args())-2)
+		for i := range vals {
+			vals[i] = "value"
+		}
+
+		c.SetVal(vals)
+		c.SetErr(nil)
+	}
+
+	return nil
+}
+
+func (*hmgetHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (*hmgetHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
+
+func TestClient_HMYield_MaxHMYieldPipelines(t *testing.T) {
+	hook := &hmgetHook{}
+	rc := redis.NewClient(&redis.Options{})
+	rc.AddHook(hook)
+
+	c := NewClient(rc, logging.NewLogger(zap.NewNop().Sugar(), time.Hour), &Options{
+		HMGetCount:          1,
+		MaxHMGetConnections: 1,
+		MaxHMYieldPipelines: 1,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Simulate two types being synchronized at the same time.
+	g, ctx := errgroup.WithContext(ctx)
+	for _, key := range []string{"icinga:host", "icinga:service"} {
+		pairs, errs := c.HMYield(ctx, key, "a", "b", "c")
+
+		g.Go(func() error {
+			for range pairs {
+			}
+
+			return <-errs
+		})
+	}
+
+	require.NoError(t, g.Wait())
+	require.Equal(t, 1, hook.max, "HMYield pipelines should not run concurrently")
+}
+
+func TestClient_HMYield_RetryTransient(t *testing.T) {
+	tests := []struct {
+		name     string
+		retries  int
+		failures int
+		wantErr  bool
+	}{
+		{name: "Recovers", retries: 3, failures: 2},
+		{name: "Exhausted", retries: 1, failures: 2, wantErr: true},
+		{name: "Disabled", retries: 0, failures: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := &hmgetHook{failures: tt.failures}
+			rc := redis.NewClient(&redis.Options{})
+			rc.AddHook(hook)
+
+			c := NewClient(rc, logging.NewLogger(zap.NewNop().Sugar(), time.Hour), &Options{
+				CommandRetries:      tt.retries,
+				CommandRetryBackoff: 2 * time.Millisecond,
+				HMGetCount:          10,
+				MaxHMGetConnections: 1,
+				MaxHMYieldPipelines: -1,
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			pairs, errs := c.HMYield(ctx, "icinga:host", "a", "b")
+
+			var fields []string
+			for pair := range pairs {
+				fields = append(fields, pair.Field)
+			}
+
+			err := <-errs
+			if tt.wantErr {
+				require.Error(t, err)
+				require.True(t, IsTransientError(err), "error should still be the LOADING error")
+				require.ErrorContains(t, err, fmt.Sprintf("after %d attempts", tt.retries+1))
+			} else {
+				require.NoError(t, err)
+				require.ElementsMatch(t, []string{"a", "b"}, fields)
+				require.Equal(t, tt.failures+1, hook.calls)
+			}
+		})
+	}
+}
+
+func TestClient_HMYield_NotTransient(t *testing.T) {
+	hook := &hmgetHook{failures: 1, failure: testRedisError("WRONGTYPE Operation against a key holding the wrong kind of value")}
+	rc := redis.NewClient(&redis.Options{})
+	rc.AddHook(hook)
+
+	c := NewClient(rc, logging.NewLogger(zap.NewNop().Sugar(), time.Hour), &Options{
+		CommandRetries:      3,
+		CommandRetryBackoff: 2 * time.Millisecond,
+		HMGetCount:          10,
+		MaxHMGetConnections: 1,
+		MaxHMYieldPipelines: -1,
+	})
+
+	pairs, errs := c.HMYield(context.Background(), "icinga:host", "a")
+	for range pairs {
+	}
+
+	err := <-errs
+	require.Error(t, err)
+	require.Equal(t, 1, hook.calls, "errors that are not transient must not be retried")
+	require.Contains(t, err.Error(), "WRONGTYPE")
+	require.NotContains(t, err.Error(), "can't retry", "error should not be wrapped by the retry")
+}
+
+func TestIsTransientError(t *testing.T) {
+	require.True(t, IsTransientError(testRedisError("LOADING Redis is loading the dataset in memory")))
+	require.True(t, IsTransientError(errors.Wrap(testRedisError("CLUSTERDOWN The cluster is down"), "HMGET")))
+	require.True(t, IsTransientError(testRedisError("TRYAGAIN Multiple keys request during rehashing of slot")))
+	require.False(t, IsTransientError(testRedisError("WRONGTYPE Operation against a key holding the wrong kind of value")))
+	require.False(t, IsTransientError(errors.New("LOADING but not a Redis error")))
+	require.False(t, IsTransientError(nil))
+}
+
+func TestClient_HMYield_PoolTimeout(t *testing.T) {
+	// The single connection of the pool never gets a response, so it stays busy until the read timeout.
+	rc := redis.NewClient(&redis.Options{
+		Dialer: func(context.Context, string, string) (net.Conn, error) {
+			conn, _ := net.Pipe()
+			return conn, nil
+		},
+		PoolSize:     1,
+		PoolTimeout:  10 * time.Millisecond,
+		ReadTimeout:  500 * time.Millisecond,
+		WriteTimeout: 500 * time.Millisecond,
+		MaxRetries:   -1,
+	})
+	defer func() { _ = rc.Close() }()
+
+	c := NewClient(rc, logging.NewLogger(zap.NewNop().Sugar(), time.Hour), &Options{
+		HMGetCount:          1,
+		MaxHMGetConnections: 2,
+		MaxHMYieldPipelines: -1,
+	})
+
+	pairs, errs := c.HMYield(context.Background(), "icinga:host", "a", "b")
+	for range pairs {
+	}
+
+	err := <-errs
+	require.Error(t, err)
+	require.True(t, IsPoolTimeout(err), "expected pool timeout, got %v", err)
+	require.Contains(t, err.Error(), "pool_size")
+}
+
+// hscanHook answers HSCAN with fields, HLEN with hlen and TTL with ttl.
+type hscanHook struct {
+	fields []string
+	hlen   int64
+	ttl    time.Duration
+}
+
+func (h *hscanHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	return ctx, errHooked
+}
+
+func (h *hscanHook) AfterProcess(_ context.Context, cmd redis.Cmder) error {
+	switch c := cmd.(type) {
+	case *redis.ScanCmd:
+		page := make([]string, 0, len(h.fields)*2)
+		for _, field := range h.fields {
+			page = append(page, field, "value")
+		}
+
+		c.SetVal(page, 0)
+	case *redis.IntCmd:
+		c.SetVal(h.hlen)
+	case *redis.DurationCmd:
+		c.SetVal(h.ttl)
+	}
+
+	cmd.SetErr(nil)
+
+	return nil
+}
+
+func (*hscanHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (*hscanHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
+
+func TestClient_HYield_VerifyHLen(t *testing.T) {
+	tests := []struct {
+		name      string
+		verify    bool
+		tolerance float64
+		hlen      int64
+		level     zapcore.Level
+		logged    bool
+	}{
+		{name: "Match", verify: true, hlen: 3},
+		{name: "Mismatch", verify: true, hlen: 5, level: zapcore.WarnLevel, logged: true},
+		{name: "WithinTolerance", verify: true, tolerance: 0.5, hlen: 5, level: zapcore.DebugLevel, logged: true},
+		{name: "ExceedsTolerance", verify: true, tolerance: 0.2, hlen: 5, level: zapcore.WarnLevel, logged: true},
+		{name: "Disabled", hlen: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := redis.NewClient(&redis.Options{})
+			// Duplicates returned by HSCAN must not be counted.
+			rc.AddHook(&hscanHook{fields: []string{"a", "b", "c", "a"}, hlen: tt.hlen})
+
+			core, logs := observer.New(zapcore.DebugLevel)
+			c := NewClient(rc, logging.NewLogger(zap.New(core).Sugar(), time.Hour), &Options{
+				HScanCount:           10,
+				VerifyHScan:          tt.verify,
+				VerifyHScanTolerance: tt.tolerance,
+			})
+
+			pairs, errs := c.HYield(context.Background(), "icinga:host")
+			for range pairs {
+			}
+			require.NoError(t, <-errs)
+
+			entries := logs.FilterMessage("Number of fields returned by HSCAN differs from HLEN").All()
+			if tt.logged {
+				require.Len(t, entries, 1)
+				require.Equal(t, tt.level, entries[0].Level)
+				require.EqualValues(t, 3, entries[0].ContextMap()["scanned"])
+				require.EqualValues(t, 5, entries[0].ContextMap()["hlen"])
+			} else {
+				require.Empty(t, entries)
+			}
+		})
+	}
+}
+
+func TestClient_YieldAll_CheckTTL(t *testing.T) {
+	tests := []struct {
+		name     string
+		checkTTL bool
+		ttl      time.Duration
+		warn     bool
+	}{
+		{name: "TTL", checkTTL: true, ttl: time.Hour, warn: true},
+		{name: "NoTTL", checkTTL: true, ttl: -1},
+		{name: "Disabled", ttl: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := redis.NewClient(&redis.Options{})
+			rc.AddHook(&hscanHook{ttl: tt.ttl})
+
+			core, logs := observer.New(zapcore.WarnLevel)
+			c := NewClient(rc, logging.NewLogger(zap.New(core).Sugar(), time.Hour), &Options{HScanCount: 10, CheckTTL: tt.checkTTL})
+
+			entities, errs := c.YieldAll(context.Background(), common.NewSyncSubject(v1.NewEndpoint))
+			for range entities {
+			}
+			require.NoError(t, <-errs)
+
+			warnings := logs.FilterMessage("Redis key has a TTL set and will expire, Icinga DB may see incomplete data").All()
+			if tt.warn {
+				require.Len(t, warnings, 1)
+				require.Equal(t, "icinga:checksum:endpoint", warnings[0].ContextMap()["key"])
+			} else {
+				require.Empty(t, warnings)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/icinga/icingadb/pkg/utils"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
+	"strings"
 )
 
 // Streams represents a Redis stream key to ID mapping.
@@ -118,6 +119,10 @@ func SetChecksums(ctx context.Context, entities <-chan contracts.Entity, checksu
 func WrapCmdErr(cmd redis.Cmder) error {
 	err := cmd.Err()
 	if err != nil {
+		if IsPoolTimeout(err) {
+			err = errors.Wrap(err, "all Redis connections are busy. Consider increasing redis.options.pool_size")
+		}
+
 		err = errors.Wrapf(err, "can't perform %q", utils.Ellipsize(
 			redis.NewCmd(context.Background(), cmd.Args()).String(), // Omits error in opposite to cmd.String()
 			100,
@@ -126,3 +131,27 @@ func WrapCmdErr(cmd redis.Cmder) error {
 
 	return err
 }
+
+// IsPoolTimeout checks whether the given error occurred because
+// no connection of the Redis connection pool became available in time.
+func IsPoolTimeout(err error) bool {
+	// go-redis does not export its pool errors, so we have to compare the message.
+	return err != nil && errors.Cause(err).Error() == "redis: connection pool timeout"
+}
+
+// IsTransientError checks whether the given error is an error reply from Redis that
+// only occurs temporarily, e.g. while Redis is loading its dataset after a restart.
+func IsTransientError(err error) bool {
+	var re redis.Error
+	if !errors.As(err, &re) {
+		return false
+	}
+
+	for _, prefix := range []string{"LOADING ", "CLUSTERDOWN ", "TRYAGAIN "} {
+		if strings.HasPrefix(re.Error(), prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,332 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const namespace = "icingadb"
+
+// startTime is the time the process has been started at, approximately.
+var startTime = time.Now()
+
+var (
+	// SyncDuration observes the duration of full syncs per type.
+	SyncDuration = NewHistogramVec(
+		namespace+"_sync_duration_seconds", "Duration of full syncs per type.", exponentialBuckets(0.01, 4, 10), "type",
+	)
+
+	// SyncRedisDuration observes the time it takes to read all entities of a type from Redis during a full sync.
+	SyncRedisDuration = NewHistogramVec(
+		namespace+"_sync_redis_duration_seconds",
+		"Time it takes to read all entities of a type from Redis during a full sync.",
+		exponentialBuckets(0.01, 4, 10), "type",
+	)
+
+	// SyncApplyDuration observes the time it takes to apply the delta of a type to the database,
+	// which includes fetching the entities to create and update from Redis.
+	SyncApplyDuration = NewHistogramVec(
+		namespace+"_sync_apply_duration_seconds",
+		"Time it takes to apply the delta of a type to the database, "+
+			"including fetching the entities to create and update from Redis.",
+		exponentialBuckets(0.01, 4, 10), "type",
+	)
+
+	// SyncRows counts the rows created, updated and deleted per type,
+	// as well as the rows not deleted because deletes are disabled, with the operation skip_delete.
+	SyncRows = NewCounterVec(
+		namespace+"_sync_rows_total",
+		"Number of rows created, updated, deleted and not deleted because deletes are disabled (skip_delete) "+
+			"per type.",
+		"type", "operation",
+	)
+)
+
+// collectors contains all metrics served by Handler in that order.
+var collectors = []collector{
+	gaugeFunc{"go_goroutines", "Number of goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	}},
+	gaugeFunc{"go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.", func() float64 {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+
+		return float64(m.HeapAlloc)
+	}},
+	gaugeFunc{"process_start_time_seconds", "Start time of the process since unix epoch in seconds.", func() float64 {
+		return float64(startTime.UnixNano()) / 1e9
+	}},
+	SyncDuration,
+	SyncRedisDuration,
+	SyncApplyDuration,
+	SyncRows,
+}
+
+// Handler returns a handler that serves all metrics under /metrics in the Prometheus text exposition format.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		bw := bufio.NewWriter(w)
+		for _, c := range collectors {
+			c.writeTo(bw)
+		}
+
+		// Errors only occur if the client has gone away, so there is no one to report them to.
+		_ = bw.Flush()
+	})
+
+	return mux
+}
+
+// collector writes metrics in the Prometheus text exposition format.
+type collector interface {
+	writeTo(w io.Writer)
+}
+
+// gaugeFunc is a gauge without labels whose value is determined by calling fn on each scrape.
+type gaugeFunc struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+// writeTo implements the collector interface.
+func (g gaugeFunc) writeTo(w io.Writer) {
+	writeHeader(w, g.name, g.help, "gauge")
+	writeSample(w, g.name, "", g.fn())
+}
+
+// vec is a set of time series of the same metric that differ in their label values.
+type vec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]interface{}
+}
+
+// get returns the time series with the specified label values, which it creates using create if necessary.
+func (v *vec) get(create func() interface{}, values ...string) interface{} {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+
+	pairs := make([]string, 0, len(values))
+	for i, value := range values {
+		pairs = append(pairs, v.labels[i]+`="`+labelValueEscaper.Replace(value)+`"`)
+	}
+
+	key := strings.Join(pairs, ",")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	s, ok := v.series[key]
+	if !ok {
+		s = create()
+		v.series[key] = s
+	}
+
+	return s
+}
+
+// each calls fn for each time series, sorted by their label values, with those formatted as name="value" pairs.
+func (v *vec) each(fn func(labels string, series interface{})) {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.series))
+	series := make(map[string]interface{}, len(v.series))
+	for key, s := range v.series {
+		keys = append(keys, key)
+		series[key] = s
+	}
+	v.mu.Unlock()
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		fn(key, series[key])
+	}
+}
+
+// CounterVec is a counter that is partitioned by labels.
+type CounterVec struct {
+	vec
+}
+
+// NewCounterVec returns a new CounterVec with the specified name, help text and label names.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{vec{name: name, help: help, labels: labels, series: map[string]interface{}{}}}
+}
+
+// WithLabelValues returns the Counter for the specified label values, which must match the label names in order.
+func (c *CounterVec) WithLabelValues(values ...string) *Counter {
+	return c.get(func() interface{} { return &Counter{} }, values...).(*Counter)
+}
+
+// writeTo implements the collector interface.
+func (c *CounterVec) writeTo(w io.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+	c.each(func(labels string, series interface{}) {
+		writeSample(w, c.name, labels, series.(*Counter).Value())
+	})
+}
+
+// Counter is a value that can only increase.
+type Counter struct {
+	mu    sync.Mutex
+	value float64
+}
+
+// Add adds v, which must not be negative, to the counter.
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("counter cannot decrease in value")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.value += v
+}
+
+// Value returns the current value of the counter.
+func (c *Counter) Value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.value
+}
+
+// HistogramVec is a histogram that is partitioned by labels.
+type HistogramVec struct {
+	vec
+	buckets []float64
+}
+
+// NewHistogramVec returns a new HistogramVec with the specified name, help text,
+// sorted upper bounds of the buckets and label names.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{
+		vec:     vec{name: name, help: help, labels: labels, series: map[string]interface{}{}},
+		buckets: buckets,
+	}
+}
+
+// WithLabelValues returns the Histogram for the specified label values, which must match the label names in order.
+func (h *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return h.get(func() interface{} {
+		return &Histogram{buckets: h.buckets, counts: make([]uint64, len(h.buckets))}
+	}, values...).(*Histogram)
+}
+
+// writeTo implements the collector interface.
+func (h *HistogramVec) writeTo(w io.Writer) {
+	writeHeader(w, h.name, h.help, "histogram")
+	h.each(func(labels string, series interface{}) {
+		sep := ""
+		if labels != "" {
+			sep = ","
+		}
+
+		counts, sum, count := series.(*Histogram).snapshot()
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += counts[i]
+			writeSample(w, h.name+"_bucket", labels+sep+`le="`+formatFloat(bound)+`"`, float64(cumulative))
+		}
+
+		writeSample(w, h.name+"_bucket", labels+sep+`le="+Inf"`, float64(count))
+		writeSample(w, h.name+"_sum", labels, sum)
+		writeSample(w, h.name+"_count", labels, float64(count))
+	})
+}
+
+// Histogram counts observed values in buckets and tracks their sum.
+type Histogram struct {
+	buckets []float64
+
+	mu     sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Observe adds v to the histogram.
+func (h *Histogram) Observe(v float64) {
+	// The index of the first bucket whose upper bound is not less than v, or len(buckets) for the +Inf bucket.
+	i := sort.SearchFloat64s(h.buckets, v)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+
+	h.sum += v
+	h.count++
+}
+
+// snapshot returns the non-cumulative bucket counts, the sum and the number of all observed values.
+func (h *Histogram) snapshot() ([]uint64, float64, uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]uint64(nil), h.counts...), h.sum, h.count
+}
+
+// exponentialBuckets returns count bucket upper bounds, the first being start and each following one factor times
+// the previous one.
+func exponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+
+	return buckets
+}
+
+// labelValueEscaper escapes label values as required by the Prometheus text exposition format.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeHeader writes the HELP and TYPE lines of a metric.
+func writeHeader(w io.Writer, name, help, typ string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// writeSample writes a sample line with the specified labels, which are already formatted as name="value" pairs.
+func writeSample(w io.Writer, name, labels string, value float64) {
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+
+	_, _ = fmt.Fprintf(w, "%s%s %s\n", name, labels, formatFloat(value))
+}
+
+// formatFloat formats v as required by the Prometheus text exposition format.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
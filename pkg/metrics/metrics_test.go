@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	// The metrics are global, so compare against their values before this test instead of absolute values.
+	rows := SyncRows.WithLabelValues("host", "create")
+	before := rows.Value()
+
+	rows.Add(42)
+	SyncDuration.WithLabelValues("host").Observe(1.5)
+
+	require.Equal(t, before+42, rows.Value())
+
+	srv := httptest.NewServer(Handler())
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/metrics")
+	require.NoError(t, err)
+	defer func() { _ = res.Body.Close() }()
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "text/plain; version=0.0.4; charset=utf-8", res.Header.Get("Content-Type"))
+
+	require.Contains(t, string(body), fmt.Sprintf(`icingadb_sync_rows_total{type="host",operation="create"} %v`, before+42))
+	require.Regexp(t, `icingadb_sync_duration_seconds_count\{type="host"\} [1-9]`, string(body))
+	require.Contains(t, string(body), "# TYPE go_goroutines gauge\ngo_goroutines ")
+}
+
+func TestCounterVec_writeTo(t *testing.T) {
+	c := NewCounterVec("test_total", "Help with \\ and\nnewline.", "type", "operation")
+	c.WithLabelValues("service", "update").Add(1)
+	c.WithLabelValues("host", "create").Add(2)
+	c.WithLabelValues("host", "create").Add(0.5)
+	c.WithLabelValues("with \"quotes\"", "line\nbreak\\").Add(3)
+
+	var buf bytes.Buffer
+	c.writeTo(&buf)
+
+	require.Equal(t, `# HELP test_total Help with \\ and\nnewline.
+# TYPE test_total counter
+test_total{type="host",operation="create"} 2.5
+test_total{type="service",operation="update"} 1
+test_total{type="with \"quotes\"",operation="line\nbreak\\"} 3
+`, buf.String())
+
+	require.Panics(t, func() { c.WithLabelValues("host") }, "label values should match the label names")
+	require.Panics(t, func() { c.WithLabelValues("host", "create").Add(-1) }, "counters should not decrease")
+}
+
+func TestHistogramVec_writeTo(t *testing.T) {
+	h := NewHistogramVec("test_seconds", "Help.", exponentialBuckets(1, 2, 3), "type")
+	for _, v := range []float64{0.5, 1, 3, 3.5, 10} {
+		h.WithLabelValues("host").Observe(v)
+	}
+
+	var buf bytes.Buffer
+	h.writeTo(&buf)
+
+	require.Equal(t, `# HELP test_seconds Help.
+# TYPE test_seconds histogram
+test_seconds_bucket{type="host",le="1"} 2
+test_seconds_bucket{type="host",le="2"} 2
+test_seconds_bucket{type="host",le="4"} 4
+test_seconds_bucket{type="host",le="+Inf"} 5
+test_seconds_sum{type="host"} 18
+test_seconds_count{type="host"} 5
+`, buf.String())
+}